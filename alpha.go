@@ -0,0 +1,167 @@
+package logic
+
+import (
+	"fmt"
+	"github.com/dtromb/logic/hash"
+)
+
+// Particles are alpha-equivalent if they differ only in the names of their
+// bound variables: an occurrence of a bound variable is identified by how
+// many quantifiers lie between it and the one binding it, and a free
+// variable by its name.  Particles of DeBruijnParticleSource are already
+// compared and hashed this way.
+
+// binderIndex returns the number of quantifiers between an occurrence of
+// name and the innermost of bound which binds it, or -1 if it is free.
+func binderIndex(bound []string, name string) int {
+	for i := len(bound)-1; i >= 0; i-- {
+		if bound[i] == name {
+			return len(bound)-1-i
+		}
+	}
+	return -1
+}
+
+// AlphaEquivalent reports whether a and b are equal up to the names of their
+// bound variables.
+func AlphaEquivalent(a Particle, b Particle) bool {
+	if isDeBruijn(a) && isDeBruijn(b) {
+		return a.Equals(b)
+	}
+	return alphaEquivalent(a, b, nil, nil)
+}
+
+func alphaEquivalent(a Particle, b Particle, aBound []string, bBound []string) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	switch(a.Type()) {
+		case VARIABLE: {
+			an, bn := a.(NamedParticle).String(), b.(NamedParticle).String()
+			ai, bi := binderIndex(aBound, an), binderIndex(bBound, bn)
+			if ai < 0 && bi < 0 {
+				return an == bn
+			}
+			return ai == bi
+		}
+		case QUANTIFIED_TERM, QUANTIFIED_PREDICATE: {
+			aq, bq := a.(QuantifiedParticle), b.(QuantifiedParticle)
+			return aq.Quantifier().Equals(bq.Quantifier()) &&
+				alphaEquivalent(aq.Argument(), bq.Argument(),
+					append(aBound[:len(aBound):len(aBound)], aq.Variable().String()),
+					append(bBound[:len(bBound):len(bBound)], bq.Variable().String()))
+		}
+	}
+	if a.Name() {
+		return a.Equals(b)
+	}
+	ap, bp := a.Parts(), b.Parts()
+	if len(ap) != len(bp) {
+		return false
+	}
+	for i := range ap {
+		if !alphaEquivalent(ap[i], bp[i], aBound, bBound) {
+			return false
+		}
+	}
+	return true
+}
+
+// AlphaHash returns a hash of p that is the same for alpha-equivalent
+// particles.  It is Hash() for particles without quantifiers.
+func AlphaHash(p Particle) uint64 {
+	if isDeBruijn(p) {
+		return p.Hash()
+	}
+	return alphaHash(p, nil)
+}
+
+func alphaHash(p Particle, bound []string) uint64 {
+	switch(p.Type()) {
+		case VARIABLE: {
+			if i := binderIndex(bound, p.(NamedParticle).String()); i >= 0 {
+				return (hash.FNV_PRIME * uint64(i+1)) ^ uint64(VARIABLE)
+			}
+			return p.Hash()
+		}
+		case QUANTIFIED_TERM, QUANTIFIED_PREDICATE: {
+			qp := p.(QuantifiedParticle)
+			body := alphaHash(qp.Argument(), append(bound[:len(bound):len(bound)], qp.Variable().String()))
+			return (qp.Quantifier().Hash()*3) ^ (uint64(p.Type())*5) ^ body
+		}
+	}
+	if p.Name() {
+		return p.Hash()
+	}
+	tp := p.(TupleParticle)
+	var h uint64
+	for _, arg := range tp.Arguments() {
+		h = (hash.FNV_PRIME * h) ^ alphaHash(arg, bound)
+	}
+	return (tp.Head().Hash() * 11) ^ h
+}
+
+func isDeBruijn(p Particle) bool {
+	_, ok := p.Source().(*DeBruijnParticleSource)
+	return ok
+}
+
+// Canonicalize renames the bound variables of p so that alpha-equivalent
+// particles have equal canonical forms.  The variable of a quantifier inside
+// n others is named by the (n+1)th of _1, _2, ... which is not the name of a
+// free variable of p.
+func Canonicalize(p Particle) Particle {
+	free := make(map[string]bool)
+	for _, v := range FreeVariables(p) {
+		free[v.String()] = true
+	}
+	depth := maxBinderDepth(p)
+	var names []string
+	for i := 1; len(names) < depth; i++ {
+		if name := fmt.Sprintf("_%d", i); !free[name] {
+			names = append(names, name)
+		}
+	}
+	return canonicalize(p, names, nil)
+}
+
+func maxBinderDepth(p Particle) int {
+	depth := 0
+	for _, part := range p.Parts() {
+		if d := maxBinderDepth(part); d > depth {
+			depth = d
+		}
+	}
+	if p.Type() == QUANTIFIED_TERM || p.Type() == QUANTIFIED_PREDICATE {
+		depth++
+	}
+	return depth
+}
+
+// canonicalize rebuilds p with the variables in bound, which are the names
+// of the enclosing quantifiers' variables in p, renamed to names.
+func canonicalize(p Particle, names []string, bound []string) Particle {
+	source := p.Source()
+	switch(p.Type()) {
+		case VARIABLE: {
+			if i := binderIndex(bound, p.(NamedParticle).String()); i >= 0 {
+				return source.GetVariableNamed(names[len(bound)-1-i])
+			}
+			return p
+		}
+		case QUANTIFIED_TERM, QUANTIFIED_PREDICATE: {
+			qp := p.(QuantifiedParticle)
+			v := source.GetVariableNamed(names[len(bound)])
+			body := canonicalize(qp.Argument(), names, append(bound[:len(bound):len(bound)], qp.Variable().String()))
+			return source.Get(p.Type(), qp.Quantifier(), v, body)
+		}
+	}
+	if p.Name() {
+		return p
+	}
+	parts := p.Parts()
+	for i, part := range parts {
+		parts[i] = canonicalize(part, names, bound)
+	}
+	return source.Get(p.Type(), parts...)
+}
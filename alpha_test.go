@@ -0,0 +1,45 @@
+package logic
+
+import (
+	"testing"
+)
+
+func TestAlpha(t *testing.T) {
+	source := CreateBasicParticleSource()
+	cases := []struct {
+		a string
+		b string
+		equivalent bool
+		canonical string
+	}{
+		{"forall x. P(x)", "forall y. P(y)", true, "forall _1. P(_1)"},
+		{"forall x. P(x, z)", "forall y. P(y, z)", true, "forall _1. P(_1, z)"},
+		{"forall x. P(x, z)", "forall z. P(z, z)", false, "forall _1. P(_1, z)"},
+		{"forall x y. P(x, y)", "forall y x. P(y, x)", true, "forall _1 _2. P(_1, _2)"},
+		{"forall x y. P(x, y)", "forall x y. P(y, x)", false, "forall _1 _2. P(_1, _2)"},
+		{"forall x. exists x. P(x)", "forall y. exists z. P(z)", true, "forall _1. exists _2. P(_2)"},
+		{"(forall x. P(x)) & Q(x)", "(forall y. P(y)) & Q(x)", true, "(forall _1. P(_1)) & Q(x)"},
+		{"(forall x. P(x)) & Q(x)", "(forall y. P(y)) & Q(y)", false, "(forall _1. P(_1)) & Q(x)"},
+		{"forall x. P(x, _1)", "forall _2. P(_2, _1)", true, "forall _2. P(_2, _1)"},
+		{"forall x. P(x)", "exists x. P(x)", false, "forall _1. P(_1)"},
+	}
+	for _, c := range cases {
+		a, b := readInfix(t, source, c.a, false), readInfix(t, source, c.b, false)
+		if AlphaEquivalent(a, b) != c.equivalent || AlphaEquivalent(b, a) != c.equivalent {
+			t.Errorf("'%s' and '%s' equivalent: %v", c.a, c.b, AlphaEquivalent(a, b))
+		}
+		if c.equivalent && AlphaHash(a) != AlphaHash(b) {
+			t.Errorf("'%s' and '%s' hash differently", c.a, c.b)
+		}
+		ca, cb := Canonicalize(a), Canonicalize(b)
+		if writeInfix(ca) != c.canonical {
+			t.Errorf("canonicalized '%s' as '%s'", c.a, writeInfix(ca))
+		}
+		if ca.Equals(cb) != c.equivalent {
+			t.Errorf("canonical forms of '%s' and '%s' equal: %v", c.a, c.b, ca.Equals(cb))
+		}
+		if !AlphaEquivalent(a, ca) {
+			t.Errorf("'%s' is not equivalent to its canonical form", c.a)
+		}
+	}
+}
@@ -1,8 +1,12 @@
 package logic
 
 import "fmt"
+import "sync/atomic"
 import "github.com/dtromb/logic/hash"
 
+// BasicParticleSource builds a new particle on each call.  It and the
+// particles it builds are immutable, apart from cached hashes, which are
+// written atomically, so they are safe for concurrent use.
 type BasicParticleSource struct {}
 
 type BasicName struct {
@@ -21,7 +25,7 @@ type BasicTuple struct {
 	args []Particle
 	ptype ParticleType
 	source ParticleSource
- 	hashcode uint64
+	hashcode atomic.Uint64
 }
 
 type BasicQuantified struct {
@@ -37,127 +41,125 @@ func CreateBasicParticleSource() ParticleSource {
 }
 
 func (bs *BasicParticleSource) GetVariableName(name string) Name {
-	return &BasicName{name: name, ptype: VARIABLE_NAME, source: bs}
+	return newBasicName(bs, VARIABLE_NAME, name)
 }
 
 func (bs *BasicParticleSource) GetFunctionName(name string) Name {
-	return &BasicName{name: name, ptype: FUNCTION_NAME, source: bs}
+	return newBasicName(bs, FUNCTION_NAME, name)
 }
 
 func (bs *BasicParticleSource) GetPredicateName(name string) Name {
-	return &BasicName{name: name, ptype: PREDICATE_NAME, source: bs}
+	return newBasicName(bs, PREDICATE_NAME, name)
 }
 
 func (bs *BasicParticleSource) GetOperator(name string) Name {
-	return &BasicName{name: name, ptype: OPERATOR, source: bs}
+	return newBasicName(bs, OPERATOR, name)
 }
 
 func (bs *BasicParticleSource) GetQuantifier(name string) Name {
-	return &BasicName{name: name, ptype: QUANTIFIER, source: bs}
+	return newBasicName(bs, QUANTIFIER, name)
 }
 
 func (bs *BasicParticleSource) GetVariableNamed(name string) NamedParticle {
-	return &BasicVariable{name: bs.GetVariableName(name), source: bs}
+	return bs.GetVariable(bs.GetVariableName(name))
 }
 
 func (bs *BasicParticleSource) GetVariable(name Name) NamedParticle {
-	if name.Type() != VARIABLE_NAME {
-		panic("name is not a variable name")
-	}
-	return &BasicVariable{name: name, source: bs}
+	return newBasicVariable(bs, name)
 }
 
 func (bs *BasicParticleSource) GetName(nameType ParticleType, name string) Name {
-	switch(nameType) {
-		case VARIABLE_NAME: return bs.GetVariableName(name)
-		case FUNCTION_NAME: return bs.GetFunctionName(name)
-		case PREDICATE_NAME: return bs.GetFunctionName(name)
-		case OPERATOR: return bs.GetOperator(name)
-		case QUANTIFIER: return bs.GetQuantifier(name)
-	}
-	panic("type is not a name type")
+	return getName(bs, nameType, name)
 }
 
 func (bs *BasicParticleSource) GetFunctionExpression(funcName Name, terms ...Particle) TupleParticle {
-	if funcName.Type() != FUNCTION_NAME {
-		panic("name is not a function name")
-	}
-	t := make([]Particle, len(terms))
-	for i, term := range terms {
-		if !term.Term() {
-			panic(fmt.Sprintf("expression argument %d is not a term", (i+1)))
-		}
-		t[i] = term
-	}
-	return &BasicTuple{ptype: FUNCTION_EXPRESSION, head: funcName, args: t, source: bs}
+	return newBasicTuple(bs, FUNCTION_EXPRESSION, funcName, terms)
 }
 
 func (bs *BasicParticleSource) GetAtomicPredicate(predName Name, terms ...Particle) TupleParticle {
-	if predName.Type() != PREDICATE_NAME {
-		panic("name is not a predicate name")
-	}
-	t := make([]Particle, len(terms))
-	for i, term := range terms {
-		if !term.Term() {
-			panic(fmt.Sprintf("predicate argument %d is not a term", (i+1)))
-		}
-		t[i] = term
-	}
-	return &BasicTuple{ptype: ATOMIC_PREDICATE, head: predName, args: t, source: bs}
+	return newBasicTuple(bs, ATOMIC_PREDICATE, predName, terms)
 }
 
 func (bs *BasicParticleSource) GetPredicateExpression(op Name, args ...Particle) TupleParticle {
-	if op.Type() != OPERATOR {
-		panic("op is not an operator")
-	}
-	t := make([]Particle, len(args))
-	for i, pred := range args {
-		if !pred.Predicate() {
-			panic(fmt.Sprintf("expression argument %d is not a predicate", (i+1)))
-		}
-		t[i] = pred
-	}
-	return &BasicTuple{ptype: PREDICATE_EXPRESSION, head: op, args: t, source: bs}
+	return newBasicTuple(bs, PREDICATE_EXPRESSION, op, args)
 }
 
 func (bs *BasicParticleSource) GetPredicateComprehension(op Name, args ...Particle) TupleParticle {
-	if op.Type() != OPERATOR {
-		panic("op is not an operator")
-	}
-	t := make([]Particle, len(args))
-	for i, pred := range args {
-		if !pred.Predicate() {
-			panic(fmt.Sprintf("comprehension argument %d is not a predicate", (i+1)))
-		}
-		t[i] = pred
-	}
-	return &BasicTuple{ptype: PREDICATE_COMPREHENSION, head: op, args: t, source: bs}
+	return newBasicTuple(bs, PREDICATE_COMPREHENSION, op, args)
 }
 
 func (bs *BasicParticleSource) GetTuple(tupleType ParticleType, head Name, args ...Particle) TupleParticle {
-	switch(tupleType) {
-		case ATOMIC_PREDICATE: return bs.GetAtomicPredicate(head, args...)
-		case FUNCTION_EXPRESSION: return bs.GetAtomicPredicate(head, args...)
-		case PREDICATE_EXPRESSION: return bs.GetPredicateExpression(head, args...)
-		case PREDICATE_COMPREHENSION: return bs.GetPredicateComprehension(head, args...)
-	}
-	panic("type is not a tuple type")
+	return getTuple(bs, tupleType, head, args)
 }
 
 func (bs *BasicParticleSource) GetQuantifiedTerm(quantifier Name, variable NamedParticle, arg Particle) QuantifiedParticle {
-	if quantifier.Type() != QUANTIFIER {
-		panic("first argument is not a quantifier")
+	return newBasicQuantified(bs, QUANTIFIED_TERM, quantifier, variable, arg)
+}
+
+func (bs *BasicParticleSource) GetQuantifiedPredicate(quantifier Name, variable NamedParticle, arg Particle) QuantifiedParticle {
+	return newBasicQuantified(bs, QUANTIFIED_PREDICATE, quantifier, variable, arg)
+}
+
+func (bs *BasicParticleSource) Get(ptype ParticleType, parts ...Particle) Particle {
+	return getParticle(bs, ptype, parts)
+}
+
+// The constructors below check their arguments and build the basic particle
+// types for any source; sources other than BasicParticleSource use them to
+// share its representation.
+
+func newBasicName(source ParticleSource, ptype ParticleType, name string) *BasicName {
+	return &BasicName{name: name, ptype: ptype, source: source}
+}
+
+func newBasicVariable(source ParticleSource, name Name) *BasicVariable {
+	if name.Type() != VARIABLE_NAME {
+		panic("name is not a variable name")
 	}
-	if variable.Type() != VARIABLE {
-		panic("second argument is not a variable")
+	return &BasicVariable{name: name, source: source}
+}
+
+func newBasicTuple(source ParticleSource, ptype ParticleType, head Name, args []Particle) *BasicTuple {
+	var kind string
+	switch(ptype) {
+		case FUNCTION_EXPRESSION: {
+			if head.Type() != FUNCTION_NAME {
+				panic("name is not a function name")
+			}
+			kind = "expression argument %d is not a term"
+		}
+		case ATOMIC_PREDICATE: {
+			if head.Type() != PREDICATE_NAME {
+				panic("name is not a predicate name")
+			}
+			kind = "predicate argument %d is not a term"
+		}
+		case PREDICATE_EXPRESSION: {
+			if head.Type() != OPERATOR {
+				panic("op is not an operator")
+			}
+			kind = "expression argument %d is not a predicate"
+		}
+		case PREDICATE_COMPREHENSION: {
+			if head.Type() != OPERATOR {
+				panic("op is not an operator")
+			}
+			kind = "comprehension argument %d is not a predicate"
+		}
+		default: panic("type is not a tuple type")
 	}
-	if !arg.Predicate() {
-		panic("third argument is not a predicate")
+	terms := ptype == FUNCTION_EXPRESSION || ptype == ATOMIC_PREDICATE
+	t := make([]Particle, len(args))
+	for i, arg := range args {
+		if terms && !arg.Term() || !terms && !arg.Predicate() {
+			panic(fmt.Sprintf(kind, (i+1)))
+		}
+		t[i] = arg
 	}
-	return &BasicQuantified{quantifier: quantifier, variable: variable, arg: arg, ptype: QUANTIFIED_TERM, source: bs}
+	return &BasicTuple{ptype: ptype, head: head, args: t, source: source}
 }
 
-func (bs *BasicParticleSource) GetQuantifiedPredicate(quantifier Name, variable NamedParticle, arg Particle) QuantifiedParticle {
+func newBasicQuantified(source ParticleSource, ptype ParticleType, quantifier Name, variable NamedParticle, arg Particle) *BasicQuantified {
 	if quantifier.Type() != QUANTIFIER {
 		panic("first argument is not a quantifier")
 	}
@@ -167,17 +169,38 @@ func (bs *BasicParticleSource) GetQuantifiedPredicate(quantifier Name, variable
 	if !arg.Predicate() {
 		panic("third argument is not a predicate")
 	}
-	return &BasicQuantified{quantifier: quantifier, variable: variable, arg: arg, ptype: QUANTIFIED_PREDICATE, source: bs}
+	return &BasicQuantified{quantifier: quantifier, variable: variable, arg: arg, ptype: ptype, source: source}
 }
 
-func (bs *BasicParticleSource) Get(ptype ParticleType, parts ...Particle) Particle {
+func getName(source ParticleSource, nameType ParticleType, name string) Name {
+	switch(nameType) {
+		case VARIABLE_NAME: return source.GetVariableName(name)
+		case FUNCTION_NAME: return source.GetFunctionName(name)
+		case PREDICATE_NAME: return source.GetPredicateName(name)
+		case OPERATOR: return source.GetOperator(name)
+		case QUANTIFIER: return source.GetQuantifier(name)
+	}
+	panic("type is not a name type")
+}
+
+func getTuple(source ParticleSource, tupleType ParticleType, head Name, args []Particle) TupleParticle {
+	switch(tupleType) {
+		case ATOMIC_PREDICATE: return source.GetAtomicPredicate(head, args...)
+		case FUNCTION_EXPRESSION: return source.GetFunctionExpression(head, args...)
+		case PREDICATE_EXPRESSION: return source.GetPredicateExpression(head, args...)
+		case PREDICATE_COMPREHENSION: return source.GetPredicateComprehension(head, args...)
+	}
+	panic("type is not a tuple type")
+}
+
+func getParticle(source ParticleSource, ptype ParticleType, parts []Particle) Particle {
 	switch(ptype) {
 		case VARIABLE: {
 			if len(parts) != 1 {
 				panic("VARIABLE construction requires exactly one argument")
 			}
 			if n, ok := parts[0].(Name); ok {
-				return bs.GetVariable(n)
+				return source.GetVariable(n)
 			}
 			panic("VARIABLE argument must be a Name")
 		}
@@ -189,7 +212,7 @@ func (bs *BasicParticleSource) Get(ptype ParticleType, parts ...Particle) Partic
 				panic("tuple construction requires at least one argument")
 			}
 			if n, ok := parts[0].(Name); ok {
-				return bs.GetTuple(ptype, n, parts[1:]...)
+				return source.GetTuple(ptype, n, parts[1:]...)
 			}
 			panic("tuple construction first argument must be a Name")
 		}
@@ -199,7 +222,7 @@ func (bs *BasicParticleSource) Get(ptype ParticleType, parts ...Particle) Partic
 			}
 			if n, ok := parts[0].(Name); ok {
 				if v, ok := parts[1].(NamedParticle); ok {
-					return bs.GetQuantifiedTerm(n, v, parts[2])
+					return source.GetQuantifiedTerm(n, v, parts[2])
 				}
 			}
 			panic(fmt.Sprintf("quantified construction first two args must be Name and NamedPredicate (got %s and %s)",
@@ -211,7 +234,7 @@ func (bs *BasicParticleSource) Get(ptype ParticleType, parts ...Particle) Partic
 			}	
 			if n, ok := parts[0].(Name); ok {
 				if v, ok := parts[1].(NamedParticle); ok {
-					return bs.GetQuantifiedPredicate(n, v, parts[2])
+					return source.GetQuantifiedPredicate(n, v, parts[2])
 				}
 			}
 			panic(fmt.Sprintf("quantified construction first two args must be Name and NamedPredicate (got %s and %s)",
@@ -231,6 +254,9 @@ func (n *BasicName) Predicate() bool { return false }
 func (n *BasicName) Name() bool { return true }
 func (n *BasicName) Hash() uint64 { return hash.HashString(n.name) ^ uint64(n.ptype)}
 func (n *BasicName) Equals(p Particle) bool { 
+	if eq, ok := internedEquals(n, p); ok {
+		return eq
+	}
 	if p.Type() != n.ptype {
 		return false
 	}
@@ -251,12 +277,16 @@ func (v *BasicVariable) Source() ParticleSource { return v.source }
 func (v *BasicVariable) Term() bool { return true }
 func (v *BasicVariable) Predicate() bool { return false }
 func (v *BasicVariable) Name() bool { return false }
-func (v *BasicVariable) Hash() uint64 { return v.Hash() ^ uint64(VARIABLE)}
+func (v *BasicVariable) Hash() uint64 { return v.name.Hash() ^ uint64(VARIABLE)}
 func (v *BasicVariable) Equals(p Particle) bool { 
+	if eq, ok := internedEquals(v, p); ok {
+		return eq
+	}
 	if p.Type() != VARIABLE {
 		return false
 	}
-	return p.(Name).String() == v.name.String()
+	o, ok := p.(NamedParticle)
+	return ok && o.String() == v.name.String()
 }
 func (v *BasicVariable) String() string { return v.name.String() }
 func (v *BasicVariable) NameParticle() Name { return v.name }
@@ -289,13 +319,19 @@ func (t *BasicTuple) Predicate() bool {
 	return false
 }
 func (t *BasicTuple) Name() bool { return false }
+// Hash is computed on first use; racing callers compute the same value.
 func (t *BasicTuple) Hash() uint64 { 
-	if t.hashcode == 0 { 
-		t.hashcode = (t.head.Hash() * 11) ^ HashParticleArray(t.args) 
+	h := t.hashcode.Load()
+	if h == 0 { 
+		h = (t.head.Hash() * 11) ^ HashParticleArray(t.args) 
+		t.hashcode.Store(h)
 	}
-	return t.hashcode
+	return h
 }
 func (t *BasicTuple) Equals(p Particle) bool { 
+	if eq, ok := internedEquals(t, p); ok {
+		return eq
+	}
 	if p.Type() != t.ptype {
 		return false
 	}
@@ -303,7 +339,7 @@ func (t *BasicTuple) Equals(p Particle) bool {
 		return true
 	}
 	if t.Hash() != p.Hash() {
-		return true
+		return false
 	}
 	tp, ok := p.(TupleParticle)
 	if !ok {
@@ -356,6 +392,9 @@ func (q *BasicQuantified) Hash() uint64 {
 	return (q.quantifier.Hash()*3) ^ (q.variable.Hash()*5) ^ q.arg.Hash()
 }
 func (q *BasicQuantified) Equals(p Particle) bool { 
+	if eq, ok := internedEquals(q, p); ok {
+		return eq
+	}
 	if p.Type() != q.ptype {
 		return false
 	}
@@ -363,6 +402,9 @@ func (q *BasicQuantified) Equals(p Particle) bool {
 	if !ok {
 		return false
 	}
+	if _, ok := p.(*deBruijnQuantified); ok {
+		return false
+	}
 	return qp.Quantifier().Equals(q.quantifier) &&
 	       qp.Variable().Equals(q.variable) &&
 		   qp.Argument().Equals(q.arg)
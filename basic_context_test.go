@@ -0,0 +1,59 @@
+package logic
+
+import (
+	"sync"
+	"testing"
+)
+
+// The concurrency tests are meant to be run with -race.
+
+func TestBasicConcurrentHash(t *testing.T) {
+	source := CreateBasicParticleSource()
+	shared := make([]Particle, 20)
+	for i := range shared {
+		shared[i] = buildFormula(source, i)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, p := range shared {
+				if p.Hash() != buildFormula(source, i).Hash() {
+					t.Errorf("hash of formula %d changed", i)
+				}
+				if !p.Equals(buildFormula(source, i)) || p.Equals(shared[(i+1)%len(shared)]) {
+					t.Errorf("formula %d compared wrongly", i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestBasicConcurrentBuild(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x := source.GetVariableNamed("x")
+	and := source.GetOperator(OP_AND)
+	parts := []Particle{source.GetAtomicPredicate(source.GetPredicateName("P"), x)}
+	results := make([]Particle, 16)
+	var wg sync.WaitGroup
+	for g := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := source.Get(PREDICATE_EXPRESSION, append([]Particle{and}, parts...)...)
+			for i := 0; i < 100; i++ {
+				p = source.GetPredicateExpression(and, p, parts[0])
+				p.Hash()
+			}
+			results[g] = p
+		}()
+	}
+	wg.Wait()
+	for _, p := range results {
+		if !p.Equals(results[0]) || p.Hash() != results[0].Hash() {
+			t.Errorf("concurrent builds differ")
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// The binary format writes a sequence of particles as a table of their
+// distinct subparticles, each written once after its parts and referring to
+// them by their index in the table:
+//
+//	magic    "LGCB"
+//	version  1 byte
+//	count    uvarint, then count entries of
+//	  type   1 byte, the ParticleType
+//	  name   uvarint length and UTF-8 bytes, for names
+//	  parts  uvarint indices, for variables (its name), quantified
+//	         particles (quantifier, variable and argument) and tuples (head,
+//	         uvarint arity, arguments)
+//	roots    uvarint count and indices of the particles written
+//	crc      CRC-32 (IEEE) of everything before it, 4 bytes big-endian
+//
+// Subparticles are shared when they are Equals, so the particles read back
+// share them too when the ParticleSource does.
+
+const BINARY_VERSION = 1
+
+var binaryMagic = []byte("LGCB")
+
+// WriteBinary writes ps in the binary format.
+func WriteBinary(out io.Writer, ps ...Particle) error {
+	var buf bytes.Buffer
+	buf.Write(binaryMagic)
+	buf.WriteByte(BINARY_VERSION)
+	table := newParticleTable()
+	var entries bytes.Buffer
+	roots := make([]int, len(ps))
+	for i, p := range ps {
+		roots[i] = writeBinaryParticle(&entries, table, p)
+	}
+	putUvarint(&buf, table.len())
+	buf.Write(entries.Bytes())
+	putUvarint(&buf, len(roots))
+	for _, r := range roots {
+		putUvarint(&buf, r)
+	}
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(buf.Bytes()))
+	buf.Write(sum[:])
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+func putUvarint(buf *bytes.Buffer, n int) {
+	var b [binary.MaxVarintLen64]byte
+	buf.Write(b[:binary.PutUvarint(b[:], uint64(n))])
+}
+
+// writeBinaryParticle writes the entries for p and any of its parts not yet
+// in the table, and returns the index of p.
+func writeBinaryParticle(buf *bytes.Buffer, table *particleTable, p Particle) int {
+	if i, ok := table.index(p); ok {
+		return i
+	}
+	var parts []int
+	for _, part := range p.Parts() {
+		parts = append(parts, writeBinaryParticle(buf, table, part))
+	}
+	buf.WriteByte(byte(p.Type()))
+	switch {
+		case p.Name(): {
+			name := p.(Name).String()
+			putUvarint(buf, len(name))
+			buf.WriteString(name)
+		}
+		case p.Type() == VARIABLE, p.Type() == QUANTIFIED_TERM, p.Type() == QUANTIFIED_PREDICATE: {
+			for _, i := range parts {
+				putUvarint(buf, i)
+			}
+		}
+		default: {
+			putUvarint(buf, parts[0])
+			putUvarint(buf, len(parts)-1)
+			for _, i := range parts[1:] {
+				putUvarint(buf, i)
+			}
+		}
+	}
+	return table.add(p)
+}
+
+type binaryDecoder struct {
+	data []byte
+	pos int
+	particles []Particle
+}
+
+// ReadBinary reads particles written by WriteBinary, building them with
+// source.  The whole input is checked against its checksum before anything
+// is built.
+func ReadBinary(source ParticleSource, in io.Reader) ([]Particle, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < len(binaryMagic)+1+4 || !bytes.Equal(data[:len(binaryMagic)], binaryMagic) {
+		return nil, errors.New("not in the binary particle format")
+	}
+	if v := data[len(binaryMagic)]; v != BINARY_VERSION {
+		return nil, errors.New(fmt.Sprintf("unsupported binary format version %d", v))
+	}
+	body := data[:len(data)-4]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(data[len(body):]) {
+		return nil, errors.New("binary particle data fails its checksum")
+	}
+	d := &binaryDecoder{data: body, pos: len(binaryMagic)+1}
+	count, err := d.count()
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < count; i++ {
+		p, err := d.particle(source)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("entry %d: %s", i, err.Error()))
+		}
+		d.particles = append(d.particles, p)
+	}
+	n, err := d.count()
+	if err != nil {
+		return nil, err
+	}
+	roots := make([]Particle, n)
+	for i := range roots {
+		if roots[i], err = d.ref(); err != nil {
+			return nil, err
+		}
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("trailing data after particles")
+	}
+	return roots, nil
+}
+
+func (d *binaryDecoder) uvarint() (int, error) {
+	n, k := binary.Uvarint(d.data[d.pos:])
+	if k <= 0 || n > uint64(len(d.data)) {
+		return 0, errors.New("malformed binary particle data")
+	}
+	d.pos += k
+	return int(n), nil
+}
+
+// count reads the length of a list, each element of which takes at least
+// a byte.
+func (d *binaryDecoder) count() (int, error) {
+	n, err := d.uvarint()
+	if err == nil && n > len(d.data)-d.pos {
+		err = errors.New("malformed binary particle data")
+	}
+	return n, err
+}
+
+// ref reads the index of an earlier entry.
+func (d *binaryDecoder) ref() (Particle, error) {
+	i, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if i >= len(d.particles) {
+		return nil, errors.New(fmt.Sprintf("reference to undefined entry %d", i))
+	}
+	return d.particles[i], nil
+}
+
+func (d *binaryDecoder) refs(n int) ([]Particle, error) {
+	ps := make([]Particle, n)
+	for i := range ps {
+		var err error
+		if ps[i], err = d.ref(); err != nil {
+			return nil, err
+		}
+	}
+	return ps, nil
+}
+
+func (d *binaryDecoder) particle(source ParticleSource) (Particle, error) {
+	if d.pos >= len(d.data) {
+		return nil, errors.New("truncated binary particle data")
+	}
+	ptype := ParticleType(d.data[d.pos])
+	d.pos += 1
+	var parts []Particle
+	var err error
+	switch(ptype) {
+		case VARIABLE_NAME, FUNCTION_NAME, PREDICATE_NAME, OPERATOR, QUANTIFIER: {
+			n, err := d.count()
+			if err != nil {
+				return nil, err
+			}
+			name := string(d.data[d.pos:d.pos+n])
+			d.pos += n
+			return source.GetName(ptype, name), nil
+		}
+		case VARIABLE: parts, err = d.refs(1)
+		case QUANTIFIED_TERM, QUANTIFIED_PREDICATE: parts, err = d.refs(3)
+		case FUNCTION_EXPRESSION, ATOMIC_PREDICATE, PREDICATE_COMPREHENSION, PREDICATE_EXPRESSION: {
+			var head []Particle
+			if head, err = d.refs(1); err != nil {
+				return nil, err
+			}
+			var arity int
+			if arity, err = d.count(); err != nil {
+				return nil, err
+			}
+			if parts, err = d.refs(arity); err != nil {
+				return nil, err
+			}
+			parts = append(head, parts...)
+		}
+		default: return nil, errors.New(fmt.Sprintf("invalid particle type %d", ptype))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkParts(ptype, parts); err != nil {
+		return nil, err
+	}
+	return source.Get(ptype, parts...), nil
+}
+
+// checkParts returns an error unless parts are the right kinds of particle
+// to build a particle of type ptype with ParticleSource.Get.
+func checkParts(ptype ParticleType, parts []Particle) error {
+	var kinds []string
+	switch(ptype) {
+		case VARIABLE: kinds = []string{VARIABLE_NAME.String()}
+		case QUANTIFIED_TERM, QUANTIFIED_PREDICATE: kinds = []string{QUANTIFIER.String(), VARIABLE.String(), "predicate"}
+		case FUNCTION_EXPRESSION: kinds = []string{FUNCTION_NAME.String(), "term"}
+		case ATOMIC_PREDICATE: kinds = []string{PREDICATE_NAME.String(), "term"}
+		case PREDICATE_COMPREHENSION, PREDICATE_EXPRESSION: kinds = []string{OPERATOR.String(), "predicate"}
+	}
+	for i, part := range parts {
+		kind := kinds[len(kinds)-1]
+		if i < len(kinds) {
+			kind = kinds[i]
+		}
+		ok := part.Type().String() == kind
+		switch(kind) {
+			case "term": ok = part.Term()
+			case "predicate": ok = part.Predicate()
+		}
+		if !ok {
+			return errors.New(fmt.Sprintf("%s part %d is not a %s", ptype.String(), i, kind))
+		}
+	}
+	return nil
+}
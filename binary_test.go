@@ -0,0 +1,101 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+// clauseSet returns n clauses over a deep shared term.
+func clauseSet(source ParticleSource, n int) []Particle {
+	f := source.GetFunctionName("f")
+	var deep Particle = source.GetVariableNamed("x")
+	for i := 0; i < 50; i++ {
+		deep = source.GetFunctionExpression(f, deep, source.GetFunctionExpression(source.GetFunctionName("c")))
+	}
+	var clauses []Particle
+	for i := 0; i < n; i++ {
+		clauses = append(clauses, source.GetPredicateExpression(source.GetOperator(OP_OR),
+			source.GetAtomicPredicate(source.GetPredicateName("P"), deep),
+			source.GetPredicateExpression(source.GetOperator(OP_NOT),
+				source.GetAtomicPredicate(source.GetPredicateName("Q"), deep, source.GetVariableNamed(strings.Repeat("y", i%5+1))))))
+	}
+	return clauses
+}
+
+func TestBinary(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x := source.GetVariableNamed("x")
+	ps := append(clauseSet(source, 200),
+		source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), x,
+			source.GetAtomicPredicate(source.GetPredicateName("R"),
+				source.GetQuantifiedTerm(source.GetQuantifier("I"), x,
+					source.GetPredicateExpression(source.GetOperator(OP_AND))),
+				source.GetPredicateComprehension(source.GetOperator(OP_AND), source.GetAtomicPredicate(source.GetPredicateName(""))))),
+		source.GetFunctionName("f"))
+	var buf bytes.Buffer
+	if err := WriteBinary(&buf, ps...); err != nil {
+		t.Fatal(err)
+	}
+	var text bytes.Buffer
+	for _, p := range ps {
+		GetStandardWriter().Write(p, &text)
+	}
+	if buf.Len()*50 > text.Len() {
+		t.Errorf("binary form is %d bytes, text %d", buf.Len(), text.Len())
+	}
+	data := buf.Bytes()
+	read, err := ReadBinary(CreateBasicParticleSource(), bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(ps) {
+		t.Fatalf("read %d particles", len(read))
+	}
+	for i, p := range read {
+		if !p.Equals(ps[i]) {
+			t.Errorf("particle %d did not round trip", i)
+		}
+	}
+	if read, err := ReadBinary(source, bytes.NewReader(nil)); err == nil || len(read) != 0 {
+		t.Error("expected error reading empty input")
+	}
+	for i := 0; i < len(data); i += 7 {
+		corrupt := bytes.Clone(data)
+		corrupt[i] ^= 0x10
+		if _, err := ReadBinary(source, bytes.NewReader(corrupt)); err == nil {
+			t.Errorf("corruption at byte %d not detected", i)
+		}
+	}
+	if _, err := ReadBinary(source, bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Error("truncation not detected")
+	}
+	version := bytes.Clone(data)
+	version[4] = 2
+	if _, err := ReadBinary(source, bytes.NewReader(version)); err == nil || !strings.Contains(err.Error(), "version 2") {
+		t.Errorf("got error %v for version 2", err)
+	}
+}
+
+func TestBinaryInvalid(t *testing.T) {
+	source := CreateBasicParticleSource()
+	withChecksum := func(body ...byte) []byte {
+		data := append([]byte("LGCB\x01"), body...)
+		return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(data))
+	}
+	for msg, data := range map[string][]byte{
+		"entry 1: reference to undefined entry 5": withChecksum(2, byte(FUNCTION_NAME), 1, 'f', byte(FUNCTION_EXPRESSION), 5, 0, 0),
+		"entry 1: function-expression part 0 is not a function-name": withChecksum(2, byte(PREDICATE_NAME), 1, 'P', byte(FUNCTION_EXPRESSION), 0, 0, 0),
+		"entry 2: atomic-predicate part 1 is not a term": withChecksum(3, byte(PREDICATE_NAME), 1, 'P',
+			byte(ATOMIC_PREDICATE), 0, 0, byte(ATOMIC_PREDICATE), 0, 1, 1, 0),
+		"entry 0: invalid particle type 0": withChecksum(1, byte(NAME), 0, 0),
+		"entry 0: malformed binary particle data": withChecksum(1, byte(VARIABLE_NAME), 9, 'x', 0),
+		"trailing data after particles": withChecksum(0, 0, 0),
+	} {
+		if _, err := ReadBinary(source, bytes.NewReader(data)); err == nil || err.Error() != msg {
+			t.Errorf("got error %v, expected %s", err, msg)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package logic
+
+import (
+	"github.com/dtromb/logic/hash"
+)
+
+// DeBruijnParticleSource builds particles in the locally nameless
+// representation: the body of a quantified particle refers to its bound
+// variable by a de Bruijn index, the number of quantifiers between the
+// occurrence and the one binding it, and only free variables have names.
+// Equals between its particles is therefore alpha-equivalence, and
+// substitution into them never renames.  A quantified particle equals only
+// another of a DeBruijnParticleSource; AlphaEquivalent compares it with
+// particles of other sources.
+//
+// Quantified particles still present the QuantifiedParticle interface: the
+// body is opened with a named variable, that of the particle the quantifier
+// was built from unless it is free in the body, in which case it is renamed
+// as Substitution does.  Indices are never visible through the interface.
+type DeBruijnParticleSource struct {}
+
+// deBruijnIndex is an occurrence of a bound variable.  It is typed VARIABLE,
+// but is not a NamedParticle, and only appears in the body of a quantified
+// particle.
+type deBruijnIndex struct {
+	index int
+	source ParticleSource
+}
+
+type deBruijnQuantified struct {
+	quantifier Name
+	hint string
+	body Particle
+	ptype ParticleType
+	source ParticleSource
+}
+
+func CreateDeBruijnParticleSource() *DeBruijnParticleSource {
+	return &DeBruijnParticleSource{}
+}
+
+// FromNamed returns p in the locally nameless representation.
+func (db *DeBruijnParticleSource) FromNamed(p Particle) Particle {
+	if p.Source() == ParticleSource(db) {
+		return p
+	}
+	return CopyParticle(db, p)
+}
+
+// ToNamed returns p built with target, which names every bound variable.
+func (db *DeBruijnParticleSource) ToNamed(target ParticleSource, p Particle) Particle {
+	return CopyParticle(target, p)
+}
+
+func (db *DeBruijnParticleSource) fromNamedAll(ps []Particle) []Particle {
+	converted := make([]Particle, len(ps))
+	for i, p := range ps {
+		converted[i] = db.FromNamed(p)
+	}
+	return converted
+}
+
+func (db *DeBruijnParticleSource) GetVariableName(name string) Name {
+	return newBasicName(db, VARIABLE_NAME, name)
+}
+
+func (db *DeBruijnParticleSource) GetFunctionName(name string) Name {
+	return newBasicName(db, FUNCTION_NAME, name)
+}
+
+func (db *DeBruijnParticleSource) GetPredicateName(name string) Name {
+	return newBasicName(db, PREDICATE_NAME, name)
+}
+
+func (db *DeBruijnParticleSource) GetOperator(name string) Name {
+	return newBasicName(db, OPERATOR, name)
+}
+
+func (db *DeBruijnParticleSource) GetQuantifier(name string) Name {
+	return newBasicName(db, QUANTIFIER, name)
+}
+
+func (db *DeBruijnParticleSource) GetVariableNamed(name string) NamedParticle {
+	return db.GetVariable(db.GetVariableName(name))
+}
+
+func (db *DeBruijnParticleSource) GetVariable(name Name) NamedParticle {
+	return newBasicVariable(db, db.FromNamed(name).(Name))
+}
+
+func (db *DeBruijnParticleSource) GetName(nameType ParticleType, name string) Name {
+	return getName(db, nameType, name)
+}
+
+func (db *DeBruijnParticleSource) GetFunctionExpression(funcName Name, terms ...Particle) TupleParticle {
+	return db.GetTuple(FUNCTION_EXPRESSION, funcName, terms...)
+}
+
+func (db *DeBruijnParticleSource) GetAtomicPredicate(predName Name, terms ...Particle) TupleParticle {
+	return db.GetTuple(ATOMIC_PREDICATE, predName, terms...)
+}
+
+func (db *DeBruijnParticleSource) GetPredicateExpression(op Name, args ...Particle) TupleParticle {
+	return db.GetTuple(PREDICATE_EXPRESSION, op, args...)
+}
+
+func (db *DeBruijnParticleSource) GetPredicateComprehension(op Name, args ...Particle) TupleParticle {
+	return db.GetTuple(PREDICATE_COMPREHENSION, op, args...)
+}
+
+func (db *DeBruijnParticleSource) GetTuple(tupleType ParticleType, head Name, args ...Particle) TupleParticle {
+	t := newBasicTuple(db, tupleType, head, args)
+	t.head = db.FromNamed(head).(Name)
+	t.args = db.fromNamedAll(t.args)
+	return t
+}
+
+func (db *DeBruijnParticleSource) GetQuantifiedTerm(quantifier Name, variable NamedParticle, arg Particle) QuantifiedParticle {
+	return db.getQuantified(QUANTIFIED_TERM, quantifier, variable, arg)
+}
+
+func (db *DeBruijnParticleSource) GetQuantifiedPredicate(quantifier Name, variable NamedParticle, arg Particle) QuantifiedParticle {
+	return db.getQuantified(QUANTIFIED_PREDICATE, quantifier, variable, arg)
+}
+
+func (db *DeBruijnParticleSource) getQuantified(ptype ParticleType, quantifier Name, variable NamedParticle, arg Particle) QuantifiedParticle {
+	// Checks the arguments.
+	newBasicQuantified(db, ptype, quantifier, variable, arg)
+	name := variable.String()
+	body := mapDeBruijn(db.FromNamed(arg), 0, func(p Particle, depth int) Particle {
+		if v, ok := p.(*BasicVariable); ok && v.String() == name {
+			return &deBruijnIndex{index: depth, source: db}
+		}
+		return p
+	})
+	return &deBruijnQuantified{quantifier: db.FromNamed(quantifier).(Name), hint: name, body: body, ptype: ptype, source: db}
+}
+
+func (db *DeBruijnParticleSource) Get(ptype ParticleType, parts ...Particle) Particle {
+	return getParticle(db, ptype, parts)
+}
+
+// mapDeBruijn rebuilds p, a particle or body of db, with f applied to each
+// variable and index, and the number of quantifiers above it within p.
+func mapDeBruijn(p Particle, depth int, f func(p Particle, depth int) Particle) Particle {
+	switch tp := p.(type) {
+		case *BasicVariable, *deBruijnIndex: return f(p, depth)
+		case *deBruijnQuantified: {
+			body := mapDeBruijn(tp.body, depth+1, f)
+			if body == tp.body {
+				return p
+			}
+			return &deBruijnQuantified{quantifier: tp.quantifier, hint: tp.hint, body: body, ptype: tp.ptype, source: tp.source}
+		}
+		case *BasicTuple: {
+			var args []Particle
+			for i, arg := range tp.args {
+				if q := mapDeBruijn(arg, depth, f); q != arg && args == nil {
+					args = append(append(make([]Particle, 0, len(tp.args)), tp.args[:i]...), q)
+				} else if args != nil {
+					args = append(args, q)
+				}
+			}
+			if args == nil {
+				return p
+			}
+			return newBasicTuple(tp.source, tp.ptype, tp.head, args)
+		}
+	}
+	return p
+}
+
+// freeNames returns the names of the free variables of a body.
+func (q *deBruijnQuantified) freeNames() map[string]bool {
+	free := make(map[string]bool)
+	mapDeBruijn(q.body, 0, func(p Particle, depth int) Particle {
+		if v, ok := p.(*BasicVariable); ok {
+			free[v.String()] = true
+		}
+		return p
+	})
+	return free
+}
+
+func (q *deBruijnQuantified) variableName() string {
+	if free := q.freeNames(); free[q.hint] {
+		return freshName(q.hint, free)
+	}
+	return q.hint
+}
+
+// substitute applies a substitution to the body, which cannot capture.
+func (q *deBruijnQuantified) substitute(terms map[string]Particle) Particle {
+	body := applySubstitution(q.body, terms)
+	if body == q.body {
+		return q
+	}
+	return &deBruijnQuantified{quantifier: q.quantifier, hint: q.hint, body: body, ptype: q.ptype, source: q.source}
+}
+
+func (q *deBruijnQuantified) Type() ParticleType { return q.ptype }
+func (q *deBruijnQuantified) Length() int { return 3 }
+func (q *deBruijnQuantified) Part(idx int) Particle {
+	switch(idx) {
+		case 0: return q.quantifier
+		case 1: return q.Variable()
+		case 2: return q.Argument()
+	}
+	return nil
+}
+func (q *deBruijnQuantified) Parts() []Particle {
+	v := q.Variable()
+	return []Particle{q.quantifier, v, q.open(v)}
+}
+func (q *deBruijnQuantified) Source() ParticleSource { return q.source }
+func (q *deBruijnQuantified) Term() bool { return q.ptype == QUANTIFIED_TERM }
+func (q *deBruijnQuantified) Predicate() bool { return q.ptype == QUANTIFIED_PREDICATE }
+func (q *deBruijnQuantified) Name() bool { return false }
+func (q *deBruijnQuantified) Hash() uint64 {
+	return (q.quantifier.Hash()*3) ^ (uint64(q.ptype)*5) ^ q.body.Hash()
+}
+func (q *deBruijnQuantified) Equals(p Particle) bool {
+	if o, ok := p.(*deBruijnQuantified); ok {
+		return o.ptype == q.ptype && o.quantifier.Equals(q.quantifier) && o.body.Equals(q.body)
+	}
+	return false
+}
+func (q *deBruijnQuantified) Quantifier() Name { return q.quantifier }
+func (q *deBruijnQuantified) Variable() NamedParticle {
+	return q.source.GetVariableNamed(q.variableName())
+}
+func (q *deBruijnQuantified) Argument() Particle { return q.open(q.Variable()) }
+
+// open returns the body with v for the bound variable.
+func (q *deBruijnQuantified) open(v NamedParticle) Particle {
+	return mapDeBruijn(q.body, 0, func(p Particle, depth int) Particle {
+		if i, ok := p.(*deBruijnIndex); ok && i.index == depth {
+			return v
+		}
+		return p
+	})
+}
+
+func (i *deBruijnIndex) Type() ParticleType { return VARIABLE }
+func (i *deBruijnIndex) Length() int { return 0 }
+func (i *deBruijnIndex) Part(idx int) Particle { return nil }
+func (i *deBruijnIndex) Parts() []Particle { return []Particle{} }
+func (i *deBruijnIndex) Source() ParticleSource { return i.source }
+func (i *deBruijnIndex) Term() bool { return true }
+func (i *deBruijnIndex) Predicate() bool { return false }
+func (i *deBruijnIndex) Name() bool { return false }
+func (i *deBruijnIndex) Hash() uint64 { return (hash.FNV_PRIME * uint64(i.index+1)) ^ uint64(VARIABLE) }
+func (i *deBruijnIndex) Equals(p Particle) bool {
+	o, ok := p.(*deBruijnIndex)
+	return ok && o.index == i.index
+}
@@ -0,0 +1,104 @@
+package logic
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDeBruijn(t *testing.T) {
+	basic := CreateBasicParticleSource()
+	db := CreateDeBruijnParticleSource()
+	texts := []string{
+		"forall x. P(x, y)",
+		"forall x. exists x. P(x) & Q(x, y)",
+		"forall x y. exists z. P(f(x, z), y) -> x = z",
+		"(forall x. P(x)) | exists y. Q(y, x)",
+		"P(c(), x)",
+	}
+	for _, text := range texts {
+		p := readInfix(t, basic, text, false)
+		q := db.FromNamed(p)
+		if q.Source() != ParticleSource(db) {
+			t.Errorf("'%s' was not converted", text)
+		}
+		if writeInfix(q) != text {
+			t.Errorf("'%s' reads back as '%s'", text, writeInfix(q))
+		}
+		if back := db.ToNamed(basic, q); !back.Equals(p) || back.Source() != basic {
+			t.Errorf("'%s' did not round trip", text)
+		}
+		if !AlphaEquivalent(q, p) || !AlphaEquivalent(p, q) || AlphaHash(q) != AlphaHash(p) {
+			t.Errorf("'%s' is not alpha-equivalent to its conversion", text)
+		}
+		if r := db.FromNamed(Canonicalize(p)); !r.Equals(q) || r.Hash() != q.Hash() {
+			t.Errorf("'%s' is not equal to its canonical form", text)
+		}
+	}
+
+	x := db.GetVariableNamed("x")
+	y := db.GetVariableNamed("y")
+	all := db.GetQuantifier(QUANT_FORALL)
+	px := db.GetQuantifiedPredicate(all, x, db.GetAtomicPredicate(db.GetPredicateName("P"), x, y))
+	py := db.GetQuantifiedPredicate(all, y, db.GetAtomicPredicate(db.GetPredicateName("P"), y, y))
+	pz := db.FromNamed(readInfix(t, basic, "forall z. P(z, y)", false))
+	if !px.Equals(pz) || px.Hash() != pz.Hash() || px.Equals(py) {
+		t.Errorf("quantified particles compared wrongly")
+	}
+	bx := readInfix(t, basic, "forall x. P(x, y)", false)
+	if px.Equals(bx) || bx.Equals(px) {
+		t.Errorf("quantified particles of different sources are equal")
+	}
+	tx, tbx := db.GetPredicateExpression(db.GetOperator(OP_NOT), px), basic.GetPredicateExpression(basic.GetOperator(OP_NOT), bx)
+	if tx.Equals(tbx) || tbx.Equals(tx) || !AlphaEquivalent(tx, tbx) {
+		t.Errorf("tuples of quantified particles compared wrongly")
+	}
+	if writeInfix(px.Argument()) != "P(x, y)" || px.Variable().String() != "x" {
+		t.Errorf("opened as '%s'", writeInfix(px.Argument()))
+	}
+	s := CreateSubstitution().Bind(basic.GetVariableNamed("y"), basic.GetFunctionExpression(basic.GetFunctionName("f"), basic.GetVariableNamed("x")))
+	sub := s.Apply(px)
+	if sub.Source() != ParticleSource(db) || writeInfix(sub) != "forall x1. P(x1, f(x))" {
+		t.Errorf("substituted to '%s'", writeInfix(sub))
+	}
+	if !AlphaEquivalent(sub, s.Apply(readInfix(t, basic, "forall x. P(x, y)", false))) {
+		t.Errorf("substitution differs from the named one")
+	}
+	iota := db.GetQuantifiedTerm(db.GetQuantifier("iota"), x, db.GetAtomicPredicate(db.GetPredicateName("P"), x))
+	eq := db.GetAtomicPredicate(db.GetPredicateName(PRED_EQUALS), iota, x)
+	w := GetInfixWriter()
+	w.Table.Quantifiers["iota"] = "iota"
+	var out bytes.Buffer
+	w.Write(eq, &out)
+	if out.String() != "(iota x. P(x)) = x" {
+		t.Errorf("wrote '%s'", out.String())
+	}
+}
+
+func TestDeBruijnIndexHidden(t *testing.T) {
+	basic := CreateBasicParticleSource()
+	db := CreateDeBruijnParticleSource()
+	var walk func(p Particle) bool
+	walk = func(p Particle) bool {
+		if _, ok := p.(*deBruijnIndex); ok {
+			return false
+		}
+		for _, part := range p.Parts() {
+			if !walk(part) {
+				return false
+			}
+		}
+		return true
+	}
+	s := CreateSubstitution().Bind(basic.GetVariableNamed("y"), basic.GetVariableNamed("x"))
+	for _, text := range []string{
+		"forall x. P(x, y)",
+		"forall x. exists x. P(x) & Q(x, y)",
+		"forall x y. exists z. P(f(x, z), y) -> x = z",
+		"~(forall x. P(x)) | exists y. Q(y, x)",
+	} {
+		q := db.FromNamed(readInfix(t, basic, text, false))
+		if !walk(q) || !walk(s.Apply(q)) {
+			t.Errorf("'%s' exposes a de Bruijn index", text)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package logic
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DOTWriter writes the syntax tree of a particle as a Graphviz digraph, with
+// a node for each particle labelled by its type, and its text if it is a
+// name, and an edge to each of its Parts(), labelled by position.  With DAG
+// set, subparticles which are Equals share a node.
+type DOTWriter struct {
+	LogicWriter
+	DAG bool
+	GraphName string
+}
+
+func GetDOTWriter() *DOTWriter {
+	return &DOTWriter{GraphName: "particle"}
+}
+
+func (dw *DOTWriter) Write(p Particle, out io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph " + dotQuote(dw.GraphName) + " {\n")
+	dw.writeNode(&b, newParticleTable(), p)
+	b.WriteString("}\n")
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// writeNode writes the node for p and those below it, returning its id.
+func (dw *DOTWriter) writeNode(b *strings.Builder, table *particleTable, p Particle) int {
+	if dw.DAG {
+		if id, ok := table.index(p); ok {
+			return id
+		}
+	}
+	id := table.add(p)
+	label := p.Type().String()
+	if p.Name() {
+		label += "\n" + p.(Name).String()
+	}
+	b.WriteString(fmt.Sprintf("\tn%d [label=%s];\n", id, dotQuote(label)))
+	for i, part := range p.Parts() {
+		child := dw.writeNode(b, table, part)
+		b.WriteString(fmt.Sprintf("\tn%d -> n%d [label=\"%d\"];\n", id, child, i))
+	}
+	return id
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, "\\", "\\\\")
+	s = strings.ReplaceAll(s, "\"", "\\\"")
+	return "\"" + strings.ReplaceAll(s, "\n", "\\n") + "\""
+}
@@ -0,0 +1,52 @@
+package logic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDOT(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x := source.GetVariableNamed("x")
+	p := source.GetAtomicPredicate(source.GetPredicateName("P\"q"), x, x)
+	dw := GetDOTWriter()
+	var buf bytes.Buffer
+	if err := dw.Write(p, &buf); err != nil {
+		t.Fatal(err)
+	}
+	tree := strings.Join([]string{
+		`digraph "particle" {`,
+		`	n0 [label="atomic-predicate"];`,
+		`	n1 [label="predicate-name\nP\"q"];`,
+		`	n0 -> n1 [label="0"];`,
+		`	n2 [label="variable"];`,
+		`	n3 [label="variable-name\nx"];`,
+		`	n2 -> n3 [label="0"];`,
+		`	n0 -> n2 [label="1"];`,
+		`	n4 [label="variable"];`,
+		`	n5 [label="variable-name\nx"];`,
+		`	n4 -> n5 [label="0"];`,
+		`	n0 -> n4 [label="2"];`,
+		`}`, ``}, "\n")
+	if buf.String() != tree {
+		t.Errorf("wrote tree:\n%s", buf.String())
+	}
+	dw.DAG = true
+	buf.Reset()
+	dw.Write(p, &buf)
+	dag := strings.Join([]string{
+		`digraph "particle" {`,
+		`	n0 [label="atomic-predicate"];`,
+		`	n1 [label="predicate-name\nP\"q"];`,
+		`	n0 -> n1 [label="0"];`,
+		`	n2 [label="variable"];`,
+		`	n3 [label="variable-name\nx"];`,
+		`	n2 -> n3 [label="0"];`,
+		`	n0 -> n2 [label="1"];`,
+		`	n0 -> n2 [label="2"];`,
+		`}`, ``}, "\n")
+	if buf.String() != dag {
+		t.Errorf("wrote DAG:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,522 @@
+package logic
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// The infix syntax writes particles in conventional notation:
+//
+//	forall x. Foo(x) -> x = y
+//
+// Variables are bare identifiers and functions are always applied, with no
+// space before the argument list, so a constant is written c().  Predicates
+// with no arguments are bare identifiers.  Operators and binary predicates
+// listed in the InfixTable are written as prefix or infix symbols; any other
+// operator is written in the braced form of the standard syntax, {op: P, Q},
+// as are comprehensions, {op; P, Q}.  Quantifiers must have a keyword in the
+// table, and keywords cannot be used as names.  Quantifier bodies extend as
+// far to the right as possible.
+
+type Associativity int
+const (
+	NON_ASSOC Associativity = iota
+	LEFT_ASSOC
+	RIGHT_ASSOC
+	NARY_ASSOC
+)
+
+// InfixOperator gives the notation for an OPERATOR name, or for a binary
+// PREDICATE_NAME if Relation is set.  Higher precedences bind tighter.  A
+// NARY_ASSOC operator reads a chain a & b & c as a single expression with
+// three arguments.  If set, Glyph is an alternative Unicode symbol, which the
+// reader accepts and the writer uses when set to.
+type InfixOperator struct {
+	Name string
+	Symbol string
+	Precedence int
+	Assoc Associativity
+	Prefix bool
+	Relation bool
+	Glyph string
+}
+
+// InfixTable holds the operators known to the infix syntax, and the keywords
+// of quantifiers by QUANTIFIER name.
+type InfixTable struct {
+	Operators []*InfixOperator
+	Quantifiers map[string]string
+}
+
+type InfixWriter struct {
+	Table *InfixTable
+	Unicode bool
+}
+
+type InfixReader struct {
+	StandardReader
+	Table *InfixTable
+	matched string
+}
+
+type infixKind int
+const (
+	infixName infixKind = iota
+	infixOperator
+	infixRelation
+	infixQuantified
+	infixBraced
+)
+
+type infixNode struct {
+	kind infixKind
+	name string
+	applied bool
+	mark string
+	variable string
+	args []*infixNode
+	start Position
+	end Position
+}
+
+func DefaultInfixTable() *InfixTable {
+	it := &InfixTable{
+		Operators: []*InfixOperator{
+			&InfixOperator{Name: PRED_EQUALS, Symbol: "=", Precedence: 50, Assoc: NON_ASSOC, Relation: true},
+			&InfixOperator{Name: OP_NOT, Symbol: "~", Precedence: 40, Prefix: true},
+			&InfixOperator{Name: OP_AND, Symbol: "&", Precedence: 30, Assoc: NARY_ASSOC},
+			&InfixOperator{Name: OP_OR, Symbol: "|", Precedence: 20, Assoc: NARY_ASSOC},
+			&InfixOperator{Name: OP_IMPLIES, Symbol: "->", Precedence: 10, Assoc: RIGHT_ASSOC},
+			&InfixOperator{Name: OP_IFF, Symbol: "<->", Precedence: 5, Assoc: NON_ASSOC},
+		},
+		Quantifiers: map[string]string{
+			QUANT_FORALL: "forall",
+			QUANT_EXISTS: "exists",
+		},
+	}
+	for _, op := range it.Operators {
+		if g, ok := Glyph(op.Name); ok {
+			op.Glyph = string(g)
+		}
+	}
+	return it
+}
+
+func GetInfixWriter() *InfixWriter {
+	return &InfixWriter{Table: DefaultInfixTable()}
+}
+
+func GetInfixReader() *InfixReader {
+	ir := InfixReader{Table: DefaultInfixTable()}
+	ir.Chain = &ir
+	ir.cur = Position{Line: 1, Col: 1}
+	return &ir
+}
+
+// Glyphs of standard operators are symbols, not identifiers, in the infix
+// syntax.
+func (ir *InfixReader) IdentifierStart(r rune) bool {
+	return isIdentifierStart(r)
+}
+
+// Operator returns the entry for an OPERATOR name, or for a PREDICATE_NAME
+// if relation is set.
+func (it *InfixTable) Operator(name string, relation bool) *InfixOperator {
+	for _, op := range it.Operators {
+		if op.Name == name && op.Relation == relation {
+			return op
+		}
+	}
+	return nil
+}
+
+func (it *InfixTable) Quantifier(keyword string) (string, bool) {
+	for q, kw := range it.Quantifiers {
+		if kw == keyword {
+			return q, true
+		}
+	}
+	return "", false
+}
+
+func isIdentifier(id string) bool {
+	if id == "" {
+		return false
+	}
+	for i, c := range id {
+		if i == 0 && !isIdentifierStart(c) || i > 0 && !isIdentifierPart(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (iw *InfixWriter) Write(p Particle, out io.Writer) error {
+	if p.Name() {
+		return GetStandardWriter().Write(p, out)
+	}
+	return emitInfix(iw.Table, iw, p, out)
+}
+
+func (iw *InfixWriter) symbol(op *InfixOperator) string {
+	if iw.Unicode && op.Glyph != "" {
+		return op.Glyph
+	}
+	return op.Symbol
+}
+
+func (iw *InfixWriter) name(b *strings.Builder, name string, ptype ParticleType) error {
+	if _, keyword := iw.Table.Quantifier(name); keyword || !isIdentifier(name) {
+		return errors.New(fmt.Sprintf("cannot write name '%s' in infix syntax", name))
+	}
+	b.WriteString(name)
+	return nil
+}
+
+func (iw *InfixWriter) braceHead(b *strings.Builder, name string, op *InfixOperator) error {
+	err := iw.name(b, name, OPERATOR)
+	if err != nil && op != nil {
+		b.WriteString(iw.symbol(op))
+		return nil
+	}
+	return err
+}
+
+func (iw *InfixWriter) prefix(b *strings.Builder, op *InfixOperator) {
+	sym := iw.symbol(op)
+	b.WriteString(sym)
+	if r, _ := utf8.DecodeLastRuneInString(sym); isIdentifierPart(r) {
+		b.WriteString(" ")
+	}
+}
+
+func (iw *InfixWriter) infix(b *strings.Builder, op *InfixOperator) {
+	b.WriteString(" ")
+	b.WriteString(iw.symbol(op))
+	b.WriteString(" ")
+}
+
+func (iw *InfixWriter) quantifier(b *strings.Builder, q string, vars []string) error {
+	keyword, ok := iw.Table.Quantifiers[q]
+	if !ok {
+		return errors.New(fmt.Sprintf("quantifier '%s' has no infix keyword", q))
+	}
+	sep := " "
+	if g, glyph := Glyph(q); iw.Unicode && glyph {
+		keyword, sep = string(g), ""
+	}
+	b.WriteString(keyword)
+	b.WriteString(sep)
+	for i, v := range vars {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		if err := iw.name(b, v, VARIABLE); err != nil {
+			return err
+		}
+	}
+	b.WriteString(". ")
+	return nil
+}
+
+func (iw *InfixWriter) punct(b *strings.Builder, tok string) {
+	switch(tok) {
+		case ",": b.WriteString(", ")
+		case "apply": 
+		default: b.WriteString(tok)
+	}
+}
+
+func (iw *InfixWriter) begin(b *strings.Builder) {}
+
+func (iw *InfixWriter) end(b *strings.Builder) {}
+
+// matchSymbol returns the operator, prefix or not as requested, whose symbol
+// or glyph is the longest match for the input, without consuming it.
+func (ir *InfixReader) matchSymbol(in *bufio.Reader, prefix bool) *InfixOperator {
+	var match *InfixOperator
+	ir.matched = ""
+	for _, op := range ir.Table.Operators {
+		if op.Prefix != prefix {
+			continue
+		}
+		for _, sym := range []string{op.Symbol, op.Glyph} {
+			if sym == "" || len(sym) <= len(ir.matched) {
+				continue
+			}
+			buf, _ := in.Peek(len(sym) + utf8.UTFMax)
+			if !strings.HasPrefix(string(buf), sym) {
+				continue
+			}
+			last, _ := utf8.DecodeLastRuneInString(sym)
+			next, _ := utf8.DecodeRune(buf[len(sym):])
+			if len(buf) > len(sym) && isIdentifierPart(last) && isIdentifierPart(next) {
+				continue
+			}
+			match, ir.matched = op, sym
+		}
+	}
+	return match
+}
+
+// consumeSymbol reads the symbol last matched.
+func (ir *InfixReader) consumeSymbol(in *bufio.Reader) {
+	ir.NextString(in, ir.matched)
+}
+
+func (ir *InfixReader) peekInfix(in *bufio.Reader) *InfixOperator {
+	ir.NextWS(in)
+	return ir.matchSymbol(in, false)
+}
+
+func (ir *InfixReader) parseExpr(in *bufio.Reader, min int) *infixNode {
+	left := ir.parsePrefix(in)
+	for {
+		op := ir.peekInfix(in)
+		if op == nil || op.Precedence < min {
+			return left
+		}
+		ir.consumeSymbol(in)
+		kind := infixOperator
+		if op.Relation {
+			kind = infixRelation
+		}
+		node := &infixNode{kind: kind, name: op.Name, args: []*infixNode{left}, start: left.start}
+		switch(op.Assoc) {
+			case LEFT_ASSOC: node.args = append(node.args, ir.parseExpr(in, op.Precedence+1))
+			case RIGHT_ASSOC: node.args = append(node.args, ir.parseExpr(in, op.Precedence))
+			case NON_ASSOC: {
+				node.args = append(node.args, ir.parseExpr(in, op.Precedence+1))
+				if next := ir.peekInfix(in); next != nil && next.Precedence == op.Precedence {
+					ir.Fail(&ParseError{Start: ir.cur, End: ir.cur, Found: next.Symbol,
+						Msg: fmt.Sprintf("operator '%s' is not associative", op.Symbol)})
+				}
+			}
+			case NARY_ASSOC: {
+				node.args = append(node.args, ir.parseExpr(in, op.Precedence+1))
+				for ir.peekInfix(in) == op {
+					ir.consumeSymbol(in)
+					node.args = append(node.args, ir.parseExpr(in, op.Precedence+1))
+				}
+			}
+		}
+		node.end = node.args[len(node.args)-1].end
+		left = node
+	}
+}
+
+func (ir *InfixReader) parseList(in *bufio.Reader, close string) []*infixNode {
+	var args []*infixNode
+	ir.NextWS(in)
+	if !ir.TestPeek(in, rune(close[0])) {
+		for {
+			args = append(args, ir.parseExpr(in, 0))
+			ir.NextWS(in)
+			if ir.TestPeek(in, rune(close[0])) {
+				break
+			}
+			if !ir.TestPeek(in, ',') {
+				ir.ErrorExpected(in, ",", close)
+			}
+			ir.NextString(in, ",")
+		}
+	}
+	ir.NextString(in, close)
+	return args
+}
+
+func (ir *InfixReader) parsePrefix(in *bufio.Reader) *infixNode {
+	ir.NextWS(in)
+	start := ir.cur
+	if op := ir.matchSymbol(in, true); op != nil {
+		ir.consumeSymbol(in)
+		arg := ir.parseExpr(in, op.Precedence)
+		return &infixNode{kind: infixOperator, name: op.Name, args: []*infixNode{arg}, start: start, end: arg.end}
+	}
+	if ir.TestPeek(in, '(') {
+		ir.NextString(in, "(")
+		node := ir.parseExpr(in, 0)
+		ir.NextWS(in)
+		if !ir.TestPeek(in, ')') {
+			ir.ErrorExpected(in, ")")
+		}
+		ir.NextString(in, ")")
+		return node
+	}
+	if ir.TestPeek(in, '{') {
+		node := &infixNode{kind: infixBraced, start: start}
+		ir.NextString(in, "{")
+		ir.NextWS(in)
+		if ir.PeekIdentifierStart(in) {
+			node.name = ir.NextIdentifier(in)
+		} else if op := ir.matchSymbol(in, true); op != nil {
+			ir.consumeSymbol(in)
+			node.name = op.Name
+		} else if op := ir.matchSymbol(in, false); op != nil && !op.Relation {
+			ir.consumeSymbol(in)
+			node.name = op.Name
+		} else {
+			ir.ErrorExpected(in, "operator")
+		}
+		ir.NextWS(in)
+		switch {
+			case ir.TestPeek(in, ':'): node.mark = ":"
+			case ir.TestPeek(in, ';'): node.mark = ";"
+			default: ir.ErrorExpected(in, ":", ";")
+		}
+		ir.NextString(in, node.mark)
+		node.args = ir.parseList(in, "}")
+		node.end = ir.cur
+		return node
+	}
+	if c, _, err := in.ReadRune(); err == nil {
+		in.UnreadRune()
+		if q, ok := GlyphName(c); ok && ir.Table.Quantifiers[q] != "" {
+			ir.NextString(in, string(c))
+			return ir.parseQuantified(in, q, start)
+		}
+	}
+	if !ir.PeekIdentifierStart(in) {
+		ir.ErrorExpected(in, "(", "{", "identifier")
+	}
+	id := ir.NextIdentifier(in)
+	if q, ok := ir.Table.Quantifier(id); ok {
+		return ir.parseQuantified(in, q, start)
+	}
+	node := &infixNode{kind: infixName, name: id, start: start, end: ir.cur}
+	if ir.TestPeek(in, '(') {
+		ir.NextString(in, "(")
+		node.applied = true
+		node.args = ir.parseList(in, ")")
+		node.end = ir.cur
+	}
+	return node
+}
+
+func (ir *InfixReader) parseQuantified(in *bufio.Reader, q string, start Position) *infixNode {
+	var vars []string
+	ir.NextWS(in)
+	if !ir.PeekIdentifierStart(in) {
+		ir.ErrorExpected(in, "identifier")
+	}
+	for ir.PeekIdentifierStart(in) {
+		vars = append(vars, ir.NextIdentifier(in))
+		ir.NextWS(in)
+	}
+	if !ir.TestPeek(in, '.') {
+		ir.ErrorExpected(in, ".", "identifier")
+	}
+	ir.NextString(in, ".")
+	node := ir.parseExpr(in, 0)
+	for i := len(vars)-1; i >= 0; i-- {
+		node = &infixNode{kind: infixQuantified, name: q, variable: vars[i], args: []*infixNode{node}, start: start, end: node.end}
+	}
+	return node
+}
+
+func (ir *InfixReader) predicate(source ParticleSource, n *infixNode) Particle {
+	var p Particle
+	switch(n.kind) {
+		case infixName: p = source.GetAtomicPredicate(source.GetPredicateName(n.name), ir.terms(source, n.args)...)
+		case infixRelation: p = source.GetAtomicPredicate(source.GetPredicateName(n.name), ir.terms(source, n.args)...)
+		case infixOperator: p = source.GetPredicateExpression(source.GetOperator(n.name), ir.predicates(source, n.args)...)
+		case infixQuantified: {
+			p = source.GetQuantifiedPredicate(source.GetQuantifier(n.name), source.GetVariableNamed(n.variable),
+				ir.predicate(source, n.args[0]))
+		}
+		case infixBraced: {
+			if n.mark != ":" {
+				ir.Fail(&ParseError{Start: n.start, End: n.end, Msg: "expected predicate (found comprehension)"})
+			}
+			p = source.GetPredicateExpression(source.GetOperator(n.name), ir.predicates(source, n.args)...)
+		}
+	}
+	ir.Record(p, n.start, n.end)
+	return p
+}
+
+func (ir *InfixReader) term(source ParticleSource, n *infixNode) Particle {
+	var p Particle
+	switch(n.kind) {
+		case infixName: {
+			if n.applied {
+				p = source.GetFunctionExpression(source.GetFunctionName(n.name), ir.terms(source, n.args)...)
+			} else {
+				p = source.GetVariableNamed(n.name)
+			}
+		}
+		case infixQuantified: {
+			p = source.GetQuantifiedTerm(source.GetQuantifier(n.name), source.GetVariableNamed(n.variable),
+				ir.predicate(source, n.args[0]))
+		}
+		case infixBraced: {
+			if n.mark != ";" {
+				ir.Fail(&ParseError{Start: n.start, End: n.end, Msg: "expected term (found predicate expression)"})
+			}
+			p = source.GetPredicateComprehension(source.GetOperator(n.name), ir.predicates(source, n.args)...)
+		}
+		default: {
+			ir.Fail(&ParseError{Start: n.start, End: n.end, Msg: fmt.Sprintf("expected term (found '%s' expression)", n.name)})
+		}
+	}
+	ir.Record(p, n.start, n.end)
+	return p
+}
+
+func (ir *InfixReader) terms(source ParticleSource, ns []*infixNode) []Particle {
+	ps := make([]Particle, len(ns))
+	for i, n := range ns {
+		ps[i] = ir.term(source, n)
+	}
+	return ps
+}
+
+func (ir *InfixReader) predicates(source ParticleSource, ns []*infixNode) []Particle {
+	ps := make([]Particle, len(ns))
+	for i, n := range ns {
+		ps[i] = ir.predicate(source, n)
+	}
+	return ps
+}
+
+func (ir *InfixReader) ReadTerm(source ParticleSource, in *bufio.Reader) (rp Particle, re error) {
+	defer ir.recoverError(&rp, &re)
+	ir.bind(source, in)
+	return ir.term(source, ir.parseExpr(in, 0)), nil
+}
+
+func (ir *InfixReader) ReadPredicate(source ParticleSource, in *bufio.Reader) (rp Particle, re error) {
+	defer ir.recoverError(&rp, &re)
+	ir.bind(source, in)
+	return ir.predicate(source, ir.parseExpr(in, 0)), nil
+}
+
+func (ir *InfixReader) ReadParticle(source ParticleSource, ptype ParticleType, in *bufio.Reader) (rp Particle, re error) {
+	var n *infixNode
+	switch(ptype) {
+		case VARIABLE, FUNCTION_EXPRESSION, PREDICATE_COMPREHENSION, QUANTIFIED_TERM: {
+			defer ir.recoverError(&rp, &re)
+			ir.bind(source, in)
+			n = ir.parseExpr(in, 0)
+			rp = ir.term(source, n)
+		}
+		case ATOMIC_PREDICATE, PREDICATE_EXPRESSION, QUANTIFIED_PREDICATE: {
+			defer ir.recoverError(&rp, &re)
+			ir.bind(source, in)
+			n = ir.parseExpr(in, 0)
+			rp = ir.predicate(source, n)
+		}
+		default: {
+			return ir.StandardReader.ReadParticle(source, ptype, in)
+		}
+	}
+	if rp.Type() != ptype {
+		ir.Fail(&ParseError{Start: n.start, End: n.end,
+			Msg: fmt.Sprintf("expected %s (found %s)", ptype.String(), rp.Type().String())})
+	}
+	return rp, nil
+}
@@ -0,0 +1,230 @@
+package logic
+
+import (
+	"errors"
+	"io"
+	"math"
+	"strings"
+)
+
+// infixNotation supplies the tokens of a rendering of the infix syntax;
+// infixEmitter decides their order, and where parentheses are needed by the
+// precedences in an InfixTable.
+type infixNotation interface {
+	// name writes a variable, function or predicate name, or an operator
+	// name in braces.
+	name(b *strings.Builder, name string, ptype ParticleType) error
+	// braceHead writes the operator of the braced form, which has an entry
+	// in the table if op is set.
+	braceHead(b *strings.Builder, name string, op *InfixOperator) error
+	prefix(b *strings.Builder, op *InfixOperator)
+	infix(b *strings.Builder, op *InfixOperator)
+	// quantifier writes the quantifier and variables that begin a
+	// quantified particle, up to its body.
+	quantifier(b *strings.Builder, q string, vars []string) error
+	// punct writes one of ( ) { } , : ; "apply", which comes between a
+	// function and its arguments, and " ", which comes between the mark of
+	// the braced form and its arguments.
+	punct(b *strings.Builder, tok string)
+	// begin and end bracket each expression and parenthesized group.
+	begin(b *strings.Builder)
+	end(b *strings.Builder)
+}
+
+type infixEmitter struct {
+	table *InfixTable
+	notation infixNotation
+}
+
+func emitInfix(table *InfixTable, notation infixNotation, p Particle, out io.Writer) error {
+	ie := &infixEmitter{table: table, notation: notation}
+	var b strings.Builder
+	if err := ie.write(&b, p, 0, false); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// binding returns the precedence of p's outermost construct, and whether it
+// extends as far right as possible.
+func (ie *infixEmitter) binding(p Particle) (int, bool) {
+	switch(p.Type()) {
+		case QUANTIFIED_TERM: fallthrough
+		case QUANTIFIED_PREDICATE: {
+			return math.MaxInt, true
+		}
+		case ATOMIC_PREDICATE: {
+			tp := p.(TupleParticle)
+			if op := ie.table.Operator(tp.Head().String(), true); op != nil && tp.Arity() == 2 {
+				return op.Precedence, false
+			}
+		}
+		case PREDICATE_EXPRESSION: {
+			tp := p.(TupleParticle)
+			if op := ie.operator(tp); op != nil {
+				return op.Precedence, false
+			}
+		}
+	}
+	return math.MaxInt, false
+}
+
+// operator returns the table entry for a predicate expression, if it can be
+// written with it.
+func (ie *infixEmitter) operator(tp TupleParticle) *InfixOperator {
+	op := ie.table.Operator(tp.Head().String(), false)
+	if op == nil {
+		return nil
+	}
+	switch {
+		case op.Prefix && tp.Arity() == 1: return op
+		case !op.Prefix && op.Assoc == NARY_ASSOC && tp.Arity() >= 2: return op
+		case !op.Prefix && tp.Arity() == 2: return op
+	}
+	return nil
+}
+
+// write writes p in a context that requires precedence at least min, and in
+// which more of the expression follows if open is set.
+func (ie *infixEmitter) write(b *strings.Builder, p Particle, min int, open bool) error {
+	prec, rightOpen := ie.binding(p)
+	if prec < min || (open && rightOpen) {
+		ie.notation.begin(b)
+		ie.notation.punct(b, "(")
+		if err := ie.writeBare(b, p, false); err != nil {
+			return err
+		}
+		ie.notation.punct(b, ")")
+		ie.notation.end(b)
+		return nil
+	}
+	return ie.writeBare(b, p, open)
+}
+
+func (ie *infixEmitter) writeList(b *strings.Builder, args []Particle) error {
+	for i, arg := range args {
+		if i > 0 {
+			ie.notation.punct(b, ",")
+		}
+		if err := ie.write(b, arg, 0, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ie *infixEmitter) writeApplied(b *strings.Builder, args []Particle) error {
+	ie.notation.punct(b, "apply")
+	ie.notation.punct(b, "(")
+	if err := ie.writeList(b, args); err != nil {
+		return err
+	}
+	ie.notation.punct(b, ")")
+	return nil
+}
+
+func (ie *infixEmitter) writeBraced(b *strings.Builder, tp TupleParticle, mark string) error {
+	ie.notation.punct(b, "{")
+	if err := ie.notation.braceHead(b, tp.Head().String(), ie.table.Operator(tp.Head().String(), false)); err != nil {
+		return err
+	}
+	ie.notation.punct(b, mark)
+	if tp.Arity() > 0 {
+		ie.notation.punct(b, " ")
+	}
+	if err := ie.writeList(b, tp.Arguments()); err != nil {
+		return err
+	}
+	ie.notation.punct(b, "}")
+	return nil
+}
+
+func (ie *infixEmitter) writeBare(b *strings.Builder, p Particle, open bool) error {
+	if p.Type() == VARIABLE {
+		return ie.notation.name(b, p.(NamedParticle).String(), VARIABLE)
+	}
+	if tp, ok := p.(TupleParticle); ok && p.Type() == ATOMIC_PREDICATE && tp.Arity() == 0 {
+		return ie.notation.name(b, tp.Head().String(), PREDICATE_NAME)
+	}
+	ie.notation.begin(b)
+	defer ie.notation.end(b)
+	switch(p.Type()) {
+		case FUNCTION_EXPRESSION: {
+			tp := p.(TupleParticle)
+			if err := ie.notation.name(b, tp.Head().String(), FUNCTION_NAME); err != nil {
+				return err
+			}
+			return ie.writeApplied(b, tp.Arguments())
+		}
+		case ATOMIC_PREDICATE: {
+			tp := p.(TupleParticle)
+			if op := ie.table.Operator(tp.Head().String(), true); op != nil && tp.Arity() == 2 {
+				return ie.writeInfix(b, op, tp.Arguments(), open)
+			}
+			if err := ie.notation.name(b, tp.Head().String(), PREDICATE_NAME); err != nil {
+				return err
+			}
+			if tp.Arity() > 0 {
+				return ie.writeApplied(b, tp.Arguments())
+			}
+		}
+		case PREDICATE_EXPRESSION: {
+			tp := p.(TupleParticle)
+			op := ie.operator(tp)
+			if op == nil {
+				return ie.writeBraced(b, tp, ":")
+			}
+			if op.Prefix {
+				ie.notation.prefix(b, op)
+				return ie.write(b, tp.Argument(0), op.Precedence, open)
+			}
+			return ie.writeInfix(b, op, tp.Arguments(), open)
+		}
+		case PREDICATE_COMPREHENSION: {
+			return ie.writeBraced(b, p.(TupleParticle), ";")
+		}
+		case QUANTIFIED_TERM: fallthrough
+		case QUANTIFIED_PREDICATE: {
+			qp := p.(QuantifiedParticle)
+			q := qp.Quantifier().String()
+			var vars []string
+			for {
+				vars = append(vars, qp.Variable().String())
+				next, ok := qp.Argument().(QuantifiedParticle)
+				if !ok || next.Type() != QUANTIFIED_PREDICATE || next.Quantifier().String() != q {
+					break
+				}
+				qp = next
+			}
+			if err := ie.notation.quantifier(b, q, vars); err != nil {
+				return err
+			}
+			return ie.write(b, qp.Argument(), 0, open)
+		}
+		default: {
+			return errors.New("attempt to write invalid particle type")
+		}
+	}
+	return nil
+}
+
+func (ie *infixEmitter) writeInfix(b *strings.Builder, op *InfixOperator, args []Particle, open bool) error {
+	left, right := op.Precedence+1, op.Precedence+1
+	switch(op.Assoc) {
+		case LEFT_ASSOC: left = op.Precedence
+		case RIGHT_ASSOC: right = op.Precedence
+	}
+	for i, arg := range args {
+		min := left
+		if i > 0 {
+			ie.notation.infix(b, op)
+			min = right
+		}
+		last := i == len(args)-1
+		if err := ie.write(b, arg, min, !last || open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestInfix(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x := source.GetVariableNamed("x")
+	y := source.GetVariableNamed("y")
+	all := source.GetQuantifier(QUANT_FORALL)
+	ex := source.GetQuantifier(QUANT_EXISTS)
+	and := source.GetOperator(OP_AND)
+	or := source.GetOperator(OP_OR)
+	not := source.GetOperator(OP_NOT)
+	impl := source.GetOperator(OP_IMPLIES)
+	iff := source.GetOperator(OP_IFF)
+	eq := source.GetPredicateName(PRED_EQUALS)
+	atom := func(name string, args ...Particle) Particle {
+		return source.GetAtomicPredicate(source.GetPredicateName(name), args...)
+	}
+	fn := func(name string, args ...Particle) Particle {
+		return source.GetFunctionExpression(source.GetFunctionName(name), args...)
+	}
+	P, Q, R := atom("P"), atom("Q"), atom("R")
+	cases := []struct {
+		p Particle
+		text string
+	}{
+		{source.GetQuantifiedPredicate(all, x, source.GetPredicateExpression(impl,
+			atom("Foo", x), source.GetAtomicPredicate(eq, x, y))), "forall x. Foo(x) -> x = y"},
+		{source.GetPredicateExpression(and, P, Q, R), "P & Q & R"},
+		{source.GetPredicateExpression(and, source.GetPredicateExpression(and, P, Q), R), "(P & Q) & R"},
+		{source.GetPredicateExpression(impl, P, source.GetPredicateExpression(impl, Q, R)), "P -> Q -> R"},
+		{source.GetPredicateExpression(impl, source.GetPredicateExpression(impl, P, Q), R), "(P -> Q) -> R"},
+		{source.GetPredicateExpression(or, source.GetPredicateExpression(and, P, Q), R), "P & Q | R"},
+		{source.GetPredicateExpression(and, source.GetPredicateExpression(or, P, Q), R), "(P | Q) & R"},
+		{source.GetPredicateExpression(not, source.GetPredicateExpression(and, P, Q)), "~(P & Q)"},
+		{source.GetPredicateExpression(and, source.GetPredicateExpression(not, P), Q), "~P & Q"},
+		{source.GetPredicateExpression(not, source.GetPredicateExpression(not, source.GetAtomicPredicate(eq, x, y))), "~~x = y"},
+		{source.GetPredicateExpression(iff, source.GetPredicateExpression(iff, P, Q), R), "(P <-> Q) <-> R"},
+		{source.GetPredicateExpression(and, source.GetQuantifiedPredicate(ex, x, atom("P", x)), Q), "(exists x. P(x)) & Q"},
+		{source.GetPredicateExpression(or, source.GetPredicateExpression(and, Q, source.GetQuantifiedPredicate(ex, x, atom("P", x))), R),
+			"Q & (exists x. P(x)) | R"},
+		{source.GetPredicateExpression(impl, P, source.GetQuantifiedPredicate(all, x, source.GetPredicateExpression(or, atom("P", x), Q))),
+			"P -> forall x. P(x) | Q"},
+		{source.GetQuantifiedPredicate(all, x, source.GetQuantifiedPredicate(all, y, source.GetQuantifiedPredicate(ex, x, atom("P", x, y)))),
+			"forall x y. exists x. P(x, y)"},
+		{source.GetAtomicPredicate(eq, fn("f", x, fn("c")), source.GetQuantifiedTerm(source.GetQuantifier("iota"), y, atom("P", y))),
+			"f(x, c()) = iota y. P(y)"},
+		{source.GetAtomicPredicate(eq, source.GetQuantifiedTerm(source.GetQuantifier("iota"), y, atom("P", y)), x),
+			"(iota y. P(y)) = x"},
+		{source.GetPredicateExpression(source.GetOperator("xor"), P, 
+			atom("Member", x, source.GetPredicateComprehension(and, atom("P", y), Q))),
+			"{xor: P, Member(x, {&; P(y), Q})}"},
+	}
+	w := GetInfixWriter()
+	w.Table.Quantifiers["iota"] = "iota"
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := w.Write(c.p, &buf); err != nil {
+			t.Errorf("%s: %s", c.text, err.Error())
+			continue
+		}
+		if buf.String() != c.text {
+			t.Errorf("wrote '%s', expected '%s'", buf.String(), c.text)
+		}
+		r := GetInfixReader()
+		r.Table = w.Table
+		p, err := r.ReadPredicate(source, bufio.NewReader(strings.NewReader(c.text)))
+		if err != nil {
+			t.Errorf("%s: %s", c.text, err.Error())
+		} else if !p.Equals(c.p) {
+			var out bytes.Buffer
+			w.Write(p, &out)
+			t.Errorf("read '%s' as '%s'", c.text, out.String())
+		}
+	}
+}
+
+func TestInfixReader(t *testing.T) {
+	source := CreateBasicParticleSource()
+	cases := []struct {
+		src string
+		std string
+	}{
+		{"forall x y. P(x) & Q(y)", "A$x:A$y:{&:P[$x],Q[$y]}::"},
+		{"~ ~ P", "{~:{~:P[]}}"},
+		{"( P(x) ) -> ((Q))", "{->:P[$x],Q[]}"},
+		{"exists x.x=f(x)", "E$x:=[$x,f($x)]:"},
+		{"P | Q & R | S", "{|:P[],{&:Q[],R[]},S[]}"},
+	}
+	for _, c := range cases {
+		p, err := GetInfixReader().ReadPredicate(source, bufio.NewReader(strings.NewReader(c.src)))
+		if err != nil {
+			t.Errorf("%s: %s", c.src, err.Error())
+			continue
+		}
+		var buf bytes.Buffer
+		GetStandardWriter().Write(p, &buf)
+		if buf.String() != c.std {
+			t.Errorf("read '%s' as '%s'", c.src, buf.String())
+		}
+	}
+	var buf bytes.Buffer
+	if err := GetInfixWriter().Write(source.GetQuantifiedPredicate(source.GetQuantifier("Q"),
+		source.GetVariableNamed("x"), source.GetAtomicPredicate(source.GetPredicateName("P"))), &buf); err == nil {
+		t.Errorf("wrote unknown quantifier as %s", buf.String())
+	}
+	for _, src := range []string{"P <-> Q <-> R", "P(x", "P(x,)", "P = ", "forall x P(x)", "forall. P", "{&: P, Q"} {
+		if _, err := GetInfixReader().ReadPredicate(source, bufio.NewReader(strings.NewReader(src))); err == nil {
+			t.Errorf("expected error reading '%s'", src)
+		}
+	}
+
+	ir := GetInfixReader()
+	ir.Table.Operator(OP_AND, false).Assoc = LEFT_ASSOC
+	p, err := ir.ReadPredicate(source, bufio.NewReader(strings.NewReader("P & Q & R")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	GetStandardWriter().Write(p, &buf)
+	if buf.String() != "{&:{&:P[],Q[]},R[]}" {
+		t.Errorf("left associative read as %s", buf.String())
+	}
+
+	var read []string
+	for p, err := range GetInfixReader().Particles(source, strings.NewReader("P & \n Q\nforall x. R(x)\n")) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		GetInfixWriter().Write(p, &buf)
+		read = append(read, buf.String())
+	}
+	if strings.Join(read, "; ") != "P & Q; forall x. R(x)" {
+		t.Errorf("streamed %v", read)
+	}
+}
+
+func TestInfixUnicode(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x := source.GetVariableNamed("x")
+	y := source.GetVariableNamed("y")
+	pred := source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), x,
+		source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), y,
+			source.GetPredicateExpression(source.GetOperator(OP_IMPLIES),
+				source.GetPredicateExpression(source.GetOperator(OP_AND),
+					source.GetAtomicPredicate(source.GetPredicateName("Foo"), x),
+					source.GetPredicateExpression(source.GetOperator(OP_NOT),
+						source.GetAtomicPredicate(source.GetPredicateName("Bar"), y))),
+				source.GetAtomicPredicate(source.GetPredicateName(PRED_EQUALS), x, y))))
+	w := GetInfixWriter()
+	w.Unicode = true
+	var buf bytes.Buffer
+	if err := w.Write(pred, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "∀x y. Foo(x) ∧ ¬Bar(y) → x = y" {
+		t.Errorf("wrote %s", buf.String())
+	}
+	for _, src := range []string{buf.String(), "forall x y. Foo(x) & ~Bar(y) -> x = y", "∀x. forall y. Foo(x) ∧ ~Bar(y) -> x = y"} {
+		p, err := GetInfixReader().ReadPredicate(source, bufio.NewReader(strings.NewReader(src)))
+		if err != nil {
+			t.Errorf("%s: %s", src, err.Error())
+		} else if !p.Equals(pred) {
+			t.Errorf("read %s incorrectly", src)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package logic
+
+import (
+	"runtime"
+	"sync"
+	"weak"
+)
+
+// InterningParticleSource hash-conses the particles it builds: equal
+// particles from the same InterningParticleSource are the same object, so
+// Equals between them is a pointer comparison.  Particles from other sources
+// are copied in when used as parts.
+//
+// The table holds its particles weakly, and forgets each once nothing else
+// refers to it, so a long-lived source only holds the particles in use.  It
+// is safe for concurrent use.
+type InterningParticleSource struct {
+	lock sync.Mutex
+	table map[uint64][]internEntry
+}
+
+// internEntry is a weak reference to one of the basic particle types.
+type internEntry struct {
+	name weak.Pointer[BasicName]
+	variable weak.Pointer[BasicVariable]
+	tuple weak.Pointer[BasicTuple]
+	quantified weak.Pointer[BasicQuantified]
+}
+
+func CreateInterningParticleSource() *InterningParticleSource {
+	return &InterningParticleSource{table: make(map[uint64][]internEntry)}
+}
+
+// internedEquals compares a and b by identity if they are both from the
+// same interning source; ok is false if they are not.
+func internedEquals(a Particle, b Particle) (equal bool, ok bool) {
+	if _, interned := a.Source().(*InterningParticleSource); interned && a.Source() == b.Source() {
+		return a == b, true
+	}
+	return false, false
+}
+
+// Intern returns the particle of the source that is equal to p, copying p in
+// if it is from another source.
+func (is *InterningParticleSource) Intern(p Particle) Particle {
+	if p.Source() == ParticleSource(is) {
+		return p
+	}
+	return CopyParticle(is, p)
+}
+
+// Len returns the number of particles in the table which are still in use.
+func (is *InterningParticleSource) Len() int {
+	is.lock.Lock()
+	defer is.lock.Unlock()
+	n := 0
+	for _, entries := range is.table {
+		for _, e := range entries {
+			if e.get() != nil {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func (e internEntry) get() Particle {
+	switch {
+		case e.name != weak.Pointer[BasicName]{}: {
+			if p := e.name.Value(); p != nil {
+				return p
+			}
+		}
+		case e.variable != weak.Pointer[BasicVariable]{}: {
+			if p := e.variable.Value(); p != nil {
+				return p
+			}
+		}
+		case e.tuple != weak.Pointer[BasicTuple]{}: {
+			if p := e.tuple.Value(); p != nil {
+				return p
+			}
+		}
+		case e.quantified != weak.Pointer[BasicQuantified]{}: {
+			if p := e.quantified.Value(); p != nil {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// sameParts reports whether a and b have the same type and name or parts,
+// which are compared by identity.
+func sameParts(a Particle, b Particle) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	if a.Name() {
+		return a.(Name).String() == b.(Name).String()
+	}
+	ap, bp := a.Parts(), b.Parts()
+	if len(ap) != len(bp) {
+		return false
+	}
+	for i := range ap {
+		if ap[i] != bp[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// intern returns the particle in the table with the same parts as p, which
+// must already be interned, adding p if there is none.
+func (is *InterningParticleSource) intern(p Particle) Particle {
+	h := p.Hash()
+	is.lock.Lock()
+	defer is.lock.Unlock()
+	for _, e := range is.table[h] {
+		if q := e.get(); q != nil && sameParts(q, p) {
+			return q
+		}
+	}
+	var e internEntry
+	switch tp := p.(type) {
+		case *BasicName: {
+			e.name = weak.Make(tp)
+			runtime.AddCleanup(tp, is.evict, h)
+		}
+		case *BasicVariable: {
+			e.variable = weak.Make(tp)
+			runtime.AddCleanup(tp, is.evict, h)
+		}
+		case *BasicTuple: {
+			e.tuple = weak.Make(tp)
+			runtime.AddCleanup(tp, is.evict, h)
+		}
+		case *BasicQuantified: {
+			e.quantified = weak.Make(tp)
+			runtime.AddCleanup(tp, is.evict, h)
+		}
+	}
+	is.table[h] = append(is.table[h], e)
+	return p
+}
+
+// evict drops the entries with hash h whose particles have been collected.
+func (is *InterningParticleSource) evict(h uint64) {
+	is.lock.Lock()
+	defer is.lock.Unlock()
+	var live []internEntry
+	for _, e := range is.table[h] {
+		if e.get() != nil {
+			live = append(live, e)
+		}
+	}
+	if len(live) == 0 {
+		delete(is.table, h)
+	} else {
+		is.table[h] = live
+	}
+}
+
+func (is *InterningParticleSource) internAll(ps []Particle) []Particle {
+	interned := make([]Particle, len(ps))
+	for i, p := range ps {
+		interned[i] = is.Intern(p)
+	}
+	return interned
+}
+
+func (is *InterningParticleSource) GetVariableName(name string) Name {
+	return is.intern(newBasicName(is, VARIABLE_NAME, name)).(Name)
+}
+
+func (is *InterningParticleSource) GetFunctionName(name string) Name {
+	return is.intern(newBasicName(is, FUNCTION_NAME, name)).(Name)
+}
+
+func (is *InterningParticleSource) GetPredicateName(name string) Name {
+	return is.intern(newBasicName(is, PREDICATE_NAME, name)).(Name)
+}
+
+func (is *InterningParticleSource) GetOperator(name string) Name {
+	return is.intern(newBasicName(is, OPERATOR, name)).(Name)
+}
+
+func (is *InterningParticleSource) GetQuantifier(name string) Name {
+	return is.intern(newBasicName(is, QUANTIFIER, name)).(Name)
+}
+
+func (is *InterningParticleSource) GetVariableNamed(name string) NamedParticle {
+	return is.GetVariable(is.GetVariableName(name))
+}
+
+func (is *InterningParticleSource) GetVariable(name Name) NamedParticle {
+	return is.intern(newBasicVariable(is, is.Intern(name).(Name))).(NamedParticle)
+}
+
+func (is *InterningParticleSource) GetName(nameType ParticleType, name string) Name {
+	return getName(is, nameType, name)
+}
+
+func (is *InterningParticleSource) GetFunctionExpression(funcName Name, terms ...Particle) TupleParticle {
+	return is.GetTuple(FUNCTION_EXPRESSION, funcName, terms...)
+}
+
+func (is *InterningParticleSource) GetAtomicPredicate(predName Name, terms ...Particle) TupleParticle {
+	return is.GetTuple(ATOMIC_PREDICATE, predName, terms...)
+}
+
+func (is *InterningParticleSource) GetPredicateExpression(op Name, args ...Particle) TupleParticle {
+	return is.GetTuple(PREDICATE_EXPRESSION, op, args...)
+}
+
+func (is *InterningParticleSource) GetPredicateComprehension(op Name, args ...Particle) TupleParticle {
+	return is.GetTuple(PREDICATE_COMPREHENSION, op, args...)
+}
+
+func (is *InterningParticleSource) GetTuple(tupleType ParticleType, head Name, args ...Particle) TupleParticle {
+	t := newBasicTuple(is, tupleType, head, args)
+	t.head = is.Intern(head).(Name)
+	t.args = is.internAll(t.args)
+	return is.intern(t).(TupleParticle)
+}
+
+func (is *InterningParticleSource) GetQuantifiedTerm(quantifier Name, variable NamedParticle, arg Particle) QuantifiedParticle {
+	return is.getQuantified(QUANTIFIED_TERM, quantifier, variable, arg)
+}
+
+func (is *InterningParticleSource) GetQuantifiedPredicate(quantifier Name, variable NamedParticle, arg Particle) QuantifiedParticle {
+	return is.getQuantified(QUANTIFIED_PREDICATE, quantifier, variable, arg)
+}
+
+func (is *InterningParticleSource) getQuantified(ptype ParticleType, quantifier Name, variable NamedParticle, arg Particle) QuantifiedParticle {
+	q := newBasicQuantified(is, ptype, quantifier, variable, arg)
+	q.quantifier = is.Intern(quantifier).(Name)
+	q.variable = is.Intern(variable).(NamedParticle)
+	q.arg = is.Intern(arg)
+	return is.intern(q).(QuantifiedParticle)
+}
+
+func (is *InterningParticleSource) Get(ptype ParticleType, parts ...Particle) Particle {
+	return getParticle(is, ptype, parts)
+}
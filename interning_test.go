@@ -0,0 +1,91 @@
+package logic
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func buildFormula(source ParticleSource, n int) Particle {
+	x := source.GetVariableNamed("x")
+	f := source.GetFunctionExpression(source.GetFunctionName(fmt.Sprintf("f%d", n)), x)
+	return source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), x,
+		source.GetPredicateExpression(source.GetOperator(OP_IMPLIES),
+			source.GetAtomicPredicate(source.GetPredicateName("P"), x, f),
+			source.GetAtomicPredicate(source.GetPredicateName(PRED_EQUALS), f, x)))
+}
+
+func TestInterning(t *testing.T) {
+	is := CreateInterningParticleSource()
+	a, b := buildFormula(is, 0), buildFormula(is, 0)
+	if a != b {
+		t.Errorf("equal particles are distinct")
+	}
+	if c := buildFormula(is, 1); c == a || c.Equals(a) {
+		t.Errorf("different particles are equal")
+	}
+	basic := buildFormula(CreateBasicParticleSource(), 0)
+	if !a.Equals(basic) || !basic.Equals(a) {
+		t.Errorf("interned particle is not equal to its basic copy")
+	}
+	if is.Intern(basic) != a || is.Intern(a) != a {
+		t.Errorf("Intern did not return the interned particle")
+	}
+	pred := a.(QuantifiedParticle).Argument().(TupleParticle)
+	if pred.Argument(0).(TupleParticle).Argument(1) != pred.Argument(1).(TupleParticle).Argument(0) {
+		t.Errorf("subterms are not shared")
+	}
+	mixed := is.GetPredicateExpression(is.GetOperator(OP_NOT), basic.(QuantifiedParticle).Argument())
+	if mixed.Argument(0) != a.(QuantifiedParticle).Argument() {
+		t.Errorf("argument from another source was not interned")
+	}
+}
+
+func TestInterningEviction(t *testing.T) {
+	is := CreateInterningParticleSource()
+	keep := buildFormula(is, 0)
+	kept := is.Len()
+	for i := 1; i <= 100; i++ {
+		buildFormula(is, i)
+	}
+	if is.Len() <= kept {
+		t.Fatalf("table holds %d particles", is.Len())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for is.Len() > kept && time.Now().Before(deadline) {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if is.Len() != kept {
+		t.Errorf("table holds %d particles after collection, expected %d", is.Len(), kept)
+	}
+	if buildFormula(is, 0) != keep {
+		t.Errorf("live particle was evicted")
+	}
+	runtime.KeepAlive(keep)
+}
+
+func TestInterningConcurrent(t *testing.T) {
+	is := CreateInterningParticleSource()
+	results := make([][]Particle, 8)
+	var wg sync.WaitGroup
+	for g := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				results[g] = append(results[g], buildFormula(is, i))
+			}
+		}()
+	}
+	wg.Wait()
+	for g := range results {
+		for i, p := range results[g] {
+			if p != results[0][i] {
+				t.Fatalf("goroutine %d built a distinct copy of formula %d", g, i)
+			}
+		}
+	}
+}
@@ -5,7 +5,11 @@ import (
 	"bufio"
 	"errors"
 	"io"
+	"iter"
+	"runtime"
+	"strconv"
 	"unicode"
+	"unicode/utf8"
 )
 
 type LogicWriter interface {
@@ -23,20 +27,30 @@ type LogicReader interface {
 type StandardWriter struct {
 	LogicWriter
 	Chain LogicWriter
+	Unicode bool
 }
 
 type StandardReader struct {
 	LogicReader
 	Chain LogicReader
-	line int
-	col int
-	pos int
-	errMsg string
+	Spans *SpanTable
+	CommentStart rune
+	cur Position
+	prev Position
+	tokStart Position
 	cTerm Particle
 	cString string
 	cIdentifier string
+	cIdentifierStart Position
+	cIdentifierQuoted bool
+	cIdentifierPending bool
+	quoted bool
 	cPred Particle
 	cSource ParticleSource
+	cIn *bufio.Reader
+	depth int
+	newline bool
+	prevNewline bool
 }
 
 func GetStandardWriter() *StandardWriter {
@@ -48,87 +62,89 @@ func GetStandardWriter() *StandardWriter {
 func GetStandardReader() *StandardReader{
 	sr := StandardReader{}
 	sr.Chain = &sr
+	sr.cur = Position{Line: 1, Col: 1}
 	return &sr
 }
 
 func (lw *StandardWriter) Write(p Particle, out io.Writer) error {
 	switch(p.Type()) {
 		case VARIABLE: {
-			out.Write([]byte(fmt.Sprintf("$%s", p.(NamedParticle).String())))
-		}
-		case VARIABLE_NAME: {
-			out.Write([]byte(fmt.Sprintf("'var:%s'", p.(Name).String())))
-		}
-		case FUNCTION_NAME: {
-			out.Write([]byte(fmt.Sprintf("'func:%s'", p.(Name).String())))
+			return writeString(out, fmt.Sprintf("$%s", QuoteIdentifier(p.(NamedParticle).String())))
 		}
+		case VARIABLE_NAME: fallthrough
+		case FUNCTION_NAME: fallthrough
 		case PREDICATE_NAME: {
-			out.Write([]byte(fmt.Sprintf("'pred:%s'", p.(Name).String())))
-		}
-		case OPERATOR: {
-			out.Write([]byte(fmt.Sprintf("'op:%s'", p.(Name).String())))
+			return writeString(out, fmt.Sprintf("'%s:%s'", NamePrefix(p.Type()), QuoteIdentifier(p.(Name).String())))
 		}
+		case OPERATOR: fallthrough
 		case QUANTIFIER: {
-			out.Write([]byte(fmt.Sprintf("'quant:%s'", p.(Name).String())))
+			return writeString(out, fmt.Sprintf("'%s:%s'", NamePrefix(p.Type()), lw.Symbol(p.(Name))))
 		}
 		case FUNCTION_EXPRESSION: {
 			tp := p.(TupleParticle)
-			out.Write([]byte(fmt.Sprintf("%s(",tp.Head().String())))
-			for i, t := range tp.Arguments() {
-				lw.Chain.Write(t, out)
-				if i < tp.Arity()-1 {
-					out.Write([]byte(","))
-				}
-			}
-			out.Write([]byte(")"))
+			return lw.writeTuple(out, fmt.Sprintf("%s(", QuoteIdentifier(tp.Head().String())), tp.Arguments(), ")")
 		}	
 		case ATOMIC_PREDICATE: {
 			tp := p.(TupleParticle)
-			out.Write([]byte(fmt.Sprintf("%s[",tp.Head().String())))
-			for i, t := range tp.Arguments() {
-				lw.Chain.Write(t, out)
-				if i < tp.Arity()-1 {
-					out.Write([]byte(","))
-				}
-			}
-			out.Write([]byte("]"))
-		}				
-		case PREDICATE_COMPREHENSION: {
-			tp := p.(TupleParticle)
-			out.Write([]byte(fmt.Sprintf("{%s;",tp.Head().String())))
-			for i, t := range tp.Arguments() {
-				lw.Chain.Write(t, out)
-				if i < tp.Arity()-1 {
-					out.Write([]byte(","))
-				}
-			}
-			out.Write([]byte("}"))
+			return lw.writeTuple(out, fmt.Sprintf("%s[", QuoteIdentifier(tp.Head().String())), tp.Arguments(), "]")
 		}				
+		case PREDICATE_COMPREHENSION: fallthrough
 		case PREDICATE_EXPRESSION: {
 			tp := p.(TupleParticle)
-			out.Write([]byte(fmt.Sprintf("{%s:",tp.Head().String())))
-			for i, t := range tp.Arguments() {
-				lw.Chain.Write(t, out)
-				if i < tp.Arity()-1 {
-					out.Write([]byte(","))
-				}
-			}
-			out.Write([]byte("}"))
+			return lw.writeTuple(out, fmt.Sprintf("{%s%s", lw.Symbol(tp.Head()), PredicateTupleMark(p.Type())), tp.Arguments(), "}")
 		}
 		case QUANTIFIED_TERM: fallthrough
 		case QUANTIFIED_PREDICATE: {
 			qp := p.(QuantifiedParticle)
-			out.Write([]byte(qp.Quantifier().String()))
-			lw.Chain.Write(qp.Variable(),out)
-			out.Write([]byte(":"))
-			lw.Chain.Write(qp.Argument(),out)
-			out.Write([]byte(":"))
+			if err := writeString(out, lw.Symbol(qp.Quantifier())); err != nil {
+				return err
+			}
+			if err := lw.Chain.Write(qp.Variable(), out); err != nil {
+				return err
+			}
+			if err := writeString(out, ":"); err != nil {
+				return err
+			}
+			if err := lw.Chain.Write(qp.Argument(), out); err != nil {
+				return err
+			}
+			return writeString(out, ":")
+		}
+	}
+	return errors.New("attempt to write invalid particle type")
+}
+
+func (lw *StandardWriter) writeTuple(out io.Writer, open string, args []Particle, close string) error {
+	if err := writeString(out, open); err != nil {
+		return err
+	}
+	for i, t := range args {
+		if i > 0 {
+			if err := writeString(out, ","); err != nil {
+				return err
+			}
 		}
-		default: {
-			return errors.New("attempt to write invalid particle type")
+		if err := lw.Chain.Write(t, out); err != nil {
+			return err
 		}
 	}
-	return nil
+	return writeString(out, close)
+}
+
+func writeString(out io.Writer, s string) error {
+	_, err := io.WriteString(out, s)
+	return err
+}
+
+// Symbol returns the text of an operator or quantifier name, as a glyph if
+// the writer is set to use Unicode and the name has one.
+func (lw *StandardWriter) Symbol(n Name) string {
+	if lw.Unicode {
+		if g, ok := Glyph(n.String()); ok {
+			return string(g)
+		}
+	}
+	return QuoteIdentifier(n.String())
 }
 	
 func NamePrefix(nameType ParticleType) string {
@@ -137,6 +153,7 @@ func NamePrefix(nameType ParticleType) string {
 		case QUANTIFIER: return "quant"
 		case PREDICATE_NAME: return "pred"
 		case FUNCTION_NAME: return "func"
+		case OPERATOR: return "op"
 	}
 	return ""
 }
@@ -149,31 +166,91 @@ func PredicateTupleMark(partType ParticleType) string {
 	return ""
 }
 
+func (sr *StandardReader) Position() Position {
+	return sr.cur
+}
+
+func (sr *StandardReader) bind(source ParticleSource, in *bufio.Reader) {
+	sr.cSource = source
+	if sr.cIn != in {
+		sr.cIn = in
+		sr.cur = Position{Line: 1, Col: 1}
+		sr.prev = sr.cur
+		sr.reset()
+	}
+}
+
+func (sr *StandardReader) reset() {
+	sr.cTerm = nil
+	sr.cPred = nil
+	sr.cString = ""
+	sr.cIdentifier = ""
+	sr.cIdentifierPending = false
+	sr.depth = 0
+}
+
+func (sr *StandardReader) readRune(in *bufio.Reader) (rune, error) {
+	c, k, err := in.ReadRune()
+	if err != nil {
+		return c, err
+	}
+	sr.prev = sr.cur
+	sr.prevNewline = sr.newline
+	sr.cur.Offset += k
+	if c == '\n' {
+		sr.cur.Line += 1
+		sr.cur.Col = 1
+		sr.newline = true
+	} else {
+		sr.cur.Col += 1
+		if !unicode.IsSpace(c) {
+			sr.newline = false
+		}
+	}
+	return c, nil
+}
+
+func (sr *StandardReader) unreadRune(in *bufio.Reader) {
+	if in.UnreadRune() == nil {
+		sr.cur = sr.prev
+		sr.newline = sr.prevNewline
+	}
+}
+
+func (sr *StandardReader) Fail(pe *ParseError) {
+	panic(pe)
+}
+
 func (sr *StandardReader) Error(err error) {
-	sr.errMsg = err.Error()
-	panic(sr.errMsg)
+	if pe, ok := err.(*ParseError); ok {
+		sr.Fail(pe)
+	}
+	sr.Fail(&ParseError{Start: sr.cur, End: sr.cur, Msg: err.Error()})
 }
 
 func (sr *StandardReader) NextString(in *bufio.Reader, expect string) string {
 	if sr.cString != "" {
 		if sr.cString != expect {
-			sr.Error(errors.New(fmt.Sprintf("expected '%s'", expect)))
+			sr.Fail(&ParseError{Start: sr.cur, End: sr.cur, Expected: []string{expect}, Found: sr.cString})
 		}
 		sr.cString = ""
 		return expect
 	}
-	buf := make([]byte, len(expect))
-	k, err := in.Read(buf)
-	sr.col += k
-	sr.pos += k
-	if err != nil || k != len(expect) {
-		if err == nil {
-			err = errors.New("unexpected short read")
+	start := sr.cur
+	var found []rune
+	for _, e := range expect {
+		c, err := sr.readRune(in)
+		if err != nil {
+			sr.Fail(&ParseError{Start: start, End: sr.cur, Expected: []string{expect}, Found: string(found)})
+		}
+		found = append(found, c)
+		if c != e {
+			sr.Fail(&ParseError{Start: start, End: sr.cur, Expected: []string{expect}, Found: string(found)})
 		}
-		sr.Error(errors.New(fmt.Sprintf("expected '%s' (%s)", expect, err.Error())))
 	}
-	if string(buf) != expect {
-		sr.Error(errors.New(fmt.Sprintf("expected '%s'", expect)))
+	switch(expect) {
+		case "(", "[", "{": sr.depth += 1
+		case ")", "]", "}": sr.depth -= 1
 	}
 	return expect
 }
@@ -181,21 +258,21 @@ func (sr *StandardReader) NextString(in *bufio.Reader, expect string) string {
 func (sr *StandardReader) NextWS(in *bufio.Reader) int {
 	var k int
 	for {
-		c, _, err := in.ReadRune()
+		c, err := sr.readRune(in)
 		if err != nil {
 			return k
 		}
+		if sr.CommentStart != 0 && c == sr.CommentStart {
+			for c != '\n' && err == nil {
+				c, err = sr.readRune(in)
+				k += 1
+			}
+			continue
+		}
 		if !unicode.IsSpace(c) {
-			in.UnreadRune()
+			sr.unreadRune(in)
 			break
 		}
-		sr.pos += 1
-		if c == '\n' {
-			sr.col = 1
-			sr.line += 1
-		} else {
-			sr.col += 1
-		}
 		k += 1
 	}
 	return k
@@ -210,41 +287,124 @@ func (sr *StandardReader) TestPeek(in *bufio.Reader, c rune) bool {
 	return c == c0
 }
 
+// NextIdentifier reads a name: a run of identifier characters, a run of
+// symbol characters, a single glyph, or a double-quoted string holding any
+// name at all.
 func (sr *StandardReader) NextIdentifier(in *bufio.Reader) string {
 	var id []rune	
-	if sr.cIdentifier != "" {
-		rv := sr.cIdentifier
-		sr.cIdentifier = ""
-		return rv
+	if sr.cIdentifierPending {
+		sr.cIdentifierPending = false
+		sr.tokStart = sr.cIdentifierStart
+		sr.quoted = sr.cIdentifierQuoted
+		return sr.cIdentifier
 	}
+	sr.tokStart = sr.cur
+	sr.quoted = false
 	for {
-		c, _, err := in.ReadRune()
+		c, err := sr.readRune(in)
 		if err != nil {
 			if len(id) != 0 {
 				break
 			}
-			sr.Error(errors.New(fmt.Sprintf("expected identifier (%s)", err.Error())))
+			sr.Fail(&ParseError{Start: sr.tokStart, End: sr.cur, Expected: []string{"identifier"}})
 		}
 		var ok bool
 		if len(id) == 0 {
-			ok = sr.IdentifierStart(c)
+			ok = sr.Chain.IdentifierStart(c)
+			if ok && c == '"' {
+				sr.unreadRune(in)
+				rv := sr.NextQuoted(in)
+				sr.quoted = true
+				return rv
+			}
+			if _, glyph := GlyphName(c); ok && glyph {
+				return string(c)
+			}
 		} else {
-			ok = sr.IdentifierPart(c)
+			ok = sr.identifierContinues(id[0], c)
 		}
 		if !ok {
-			in.UnreadRune()
-			if len(id) == 0 {
+			if len(id) != 0 {
+				sr.unreadRune(in)
 				break
 			}
-			sr.Error(errors.New(fmt.Sprintf("expected identifier (%s)", err.Error())))
+			sr.Fail(&ParseError{Start: sr.tokStart, End: sr.cur, Expected: []string{"identifier"}, Found: string(c)})
 		}
-		sr.pos += 1
-		sr.col += 1
 		id = append(id, c)
 	}
 	return string(id)
 }
 
+func (sr *StandardReader) identifierContinues(first rune, r rune) bool {
+	if isSymbolChar(first) {
+		return isSymbolChar(r)
+	}
+	return sr.Chain.IdentifierPart(r)
+}
+
+// NextQuoted reads a double-quoted string with Go escape sequences.
+func (sr *StandardReader) NextQuoted(in *bufio.Reader) string {
+	start := sr.cur
+	sr.tokStart = start
+	raw := []rune{'"'}
+	sr.NextString(in, "\"")
+	for {
+		c, err := sr.readRune(in)
+		if err != nil || c == '\n' {
+			sr.Fail(&ParseError{Start: start, End: sr.cur, Expected: []string{"\""}, Found: string(raw)})
+		}
+		raw = append(raw, c)
+		if c == '"' {
+			break
+		}
+		if c == '\\' {
+			if c, err = sr.readRune(in); err == nil {
+				raw = append(raw, c)
+			}
+		}
+	}
+	s, err := strconv.Unquote(string(raw))
+	if err != nil {
+		sr.Fail(&ParseError{Start: start, End: sr.cur, Msg: fmt.Sprintf("malformed string %s", string(raw))})
+	}
+	return s
+}
+
+// SymbolName returns the operator or quantifier name for an identifier read
+// in that position, translating glyphs to the names they stand for.  Quoted
+// identifiers are taken literally.
+func (sr *StandardReader) SymbolName(id string) string {
+	if sr.quoted {
+		return id
+	}
+	if r, k := utf8.DecodeRuneInString(id); k == len(id) {
+		if name, ok := GlyphName(r); ok {
+			return name
+		}
+	}
+	return id
+}
+
+func (sr *StandardReader) Record(p Particle, start Position, end Position) {
+	if sr.Spans != nil {
+		sr.Spans.Add(p, Span{Start: start, End: end})
+	}
+}
+
+func (sr *StandardReader) particleStart() Position {
+	if sr.cIdentifierPending {
+		return sr.cIdentifierStart
+	}
+	return sr.cur
+}
+
+func (sr *StandardReader) PushIdentifier(id string, start Position) {
+	sr.cIdentifier = id
+	sr.cIdentifierStart = start
+	sr.cIdentifierQuoted = sr.quoted
+	sr.cIdentifierPending = true
+}
+
 func (sr *StandardReader) NextTerm(in *bufio.Reader) Particle {
 	if sr.cTerm != nil {
 		t := sr.cTerm
@@ -253,7 +413,7 @@ func (sr *StandardReader) NextTerm(in *bufio.Reader) Particle {
 	}
 	t, err := sr.Chain.ReadTerm(sr.cSource, in)
 	if err != nil {
-		sr.Error(errors.New(fmt.Sprintf("expected term (%s)",err.Error())))
+		sr.Error(err)
 	}
 	return t
 }
@@ -266,7 +426,7 @@ func (sr *StandardReader) NextPredicate(in *bufio.Reader) Particle {
 	}
 	t, err := sr.Chain.ReadPredicate(sr.cSource, in)
 	if err != nil {
-		sr.Error(errors.New(fmt.Sprintf("expected predicate (%s)",err.Error())))
+		sr.Error(err)
 	}
 	return t
 }
@@ -286,15 +446,34 @@ func (sr *StandardReader) ReadDelimitedTermList(in *bufio.Reader, ldelim rune, r
 			sr.NextString(in, string(','))
 			sr.NextWS(in)
 		}
-	}				
+	}
+	sr.NextString(in, string(rdelim))
 	return args				
 }
 
+func (sr *StandardReader) asParseError(r any) *ParseError {
+	switch e := r.(type) {
+		case *ParseError: return e
+		case runtime.Error: panic(e)
+		case error: return &ParseError{Start: sr.cur, End: sr.cur, Msg: e.Error()}
+	}
+	return &ParseError{Start: sr.cur, End: sr.cur, Msg: fmt.Sprint(r)}
+}
+
+func (sr *StandardReader) recoverError(rp *Particle, re *error) {
+	if r := recover(); r != nil {
+		*rp = nil
+		*re = sr.asParseError(r)
+	}
+}
+
 func (sr *StandardReader) ReadParticle(source ParticleSource, ptype ParticleType, in *bufio.Reader) (rp Particle, re error) {
+	defer sr.recoverError(&rp, &re)
+	sr.bind(source, in)
+	start := sr.particleStart()
 	defer func() {
-		if r := recover(); r != nil {
-			rp = nil
-			re = errors.New(fmt.Sprintf("%s at %d:%d (pos=%d)", sr.errMsg, sr.line, sr.col, sr.pos))
+		if rp != nil {
+			sr.Record(rp, start, sr.cur)
 		}
 	}()
 	switch(ptype) {
@@ -306,35 +485,44 @@ func (sr *StandardReader) ReadParticle(source ParticleSource, ptype ParticleType
 		case VARIABLE_NAME: fallthrough
 		case FUNCTION_NAME: fallthrough
 		case PREDICATE_NAME: fallthrough
-		case OPERATOR: {
+		case OPERATOR: fallthrough
+		case QUANTIFIER: {
 			sr.NextString(in, "'")
 			key := sr.NextIdentifier(in)
 		    if key != NamePrefix(ptype) {
-				panic(fmt.Sprintf("expected '%s'", NamePrefix(ptype)))
+				sr.Fail(&ParseError{Start: sr.tokStart, End: sr.cur, Expected: []string{NamePrefix(ptype)}, Found: key})
 			}	
 			sr.NextString(in,":")
 			id := sr.NextIdentifier(in)
 			sr.NextString(in,"'")
+			if ptype == OPERATOR || ptype == QUANTIFIER {
+				id = sr.SymbolName(id)
+			}
 			return source.GetName(ptype, id), nil
 		}
 		case FUNCTION_EXPRESSION: {
 			name := sr.NextIdentifier(in)
+			head := source.GetFunctionName(name)
+			sr.Record(head, sr.tokStart, sr.cur)
 			sr.NextWS(in)
 			args := sr.ReadDelimitedTermList(in, '(', ')')
-			return source.GetFunctionExpression(source.GetFunctionName(name), args...), nil
+			return source.GetFunctionExpression(head, args...), nil
 		}
 		case ATOMIC_PREDICATE: {
 			name := sr.NextIdentifier(in)
+			head := source.GetPredicateName(name)
+			sr.Record(head, sr.tokStart, sr.cur)
 			sr.NextWS(in)
 			args := sr.ReadDelimitedTermList(in, '[', ']')
-			return source.GetAtomicPredicate(source.GetPredicateName(name), args...), nil
+			return source.GetAtomicPredicate(head, args...), nil
 		}
 		case PREDICATE_COMPREHENSION: fallthrough
 		case PREDICATE_EXPRESSION: {
 			var args []Particle
 			sr.NextString(in,"{")
 			sr.NextWS(in)
-			op := sr.NextIdentifier(in)
+			op := source.GetOperator(sr.SymbolName(sr.NextIdentifier(in)))
+			sr.Record(op, sr.tokStart, sr.cur)
 			sr.NextWS(in)
 			sr.NextString(in,PredicateTupleMark(ptype))
 			sr.NextWS(in)
@@ -346,20 +534,22 @@ func (sr *StandardReader) ReadParticle(source ParticleSource, ptype ParticleType
 					if sr.TestPeek(in,'}') {
 						break
 					}
-					if !sr.TestPeek(in,',') {
-						panic("expected ','")
-					}
+					sr.NextString(in,",")
 					sr.NextWS(in)
 				}
 			}
-			return source.GetTuple(ptype, source.GetOperator(op), args...), nil
+			sr.NextString(in,"}")
+			return source.GetTuple(ptype, op, args...), nil
 		}
 		case QUANTIFIED_PREDICATE: fallthrough
 		case QUANTIFIED_TERM: {
-			q := sr.NextIdentifier(in)
+			q := source.GetQuantifier(sr.SymbolName(sr.NextIdentifier(in)))
+			sr.Record(q, sr.tokStart, sr.cur)
 			sr.NextWS(in)
+			vstart := sr.cur
 			sr.NextString(in,"$")
-			id := sr.NextIdentifier(in)
+			v := source.GetVariableNamed(sr.NextIdentifier(in))
+			sr.Record(v, vstart, sr.cur)
 			sr.NextWS(in)
 			sr.NextString(in,":")
 			sr.NextWS(in)
@@ -367,25 +557,276 @@ func (sr *StandardReader) ReadParticle(source ParticleSource, ptype ParticleType
 			sr.NextWS(in)
 			sr.NextString(in,":")
 			if ptype == QUANTIFIED_PREDICATE {
-				return source.GetQuantifiedPredicate(source.GetQuantifier(q), 
-				                                     source.GetVariableNamed(id),
-													 arg), nil
+				return source.GetQuantifiedPredicate(q, v, arg), nil
 			} else {
-				return source.GetQuantifiedTerm(source.GetQuantifier(q), 
-				                                source.GetVariableNamed(id),
-											    arg), nil
+				return source.GetQuantifiedTerm(q, v, arg), nil
 			}
 		}
-		panic("unknown particle type")
 	}
+	sr.Error(errors.New(fmt.Sprintf("cannot read particle type %s", ptype.String())))
 	return nil,nil
 }
 
-func (sr *StandardReader) ReadPredicate(source ParticleSource, in *bufio.Reader) (rp Particle, re error) {
+// Identifiers follow the Unicode default identifier syntax (UAX #31), with
+// '_' allowed anywhere.
+func isIdentifierStart(r rune) bool {
+	return r == '_' || unicode.In(r, unicode.L, unicode.Nl, unicode.Other_ID_Start)
+}
+
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue)
+}
+
+// Runs of symbol characters are identifiers too, so that names like "=" and
+// "->" need no quoting.
+func isSymbolChar(r rune) bool {
+	switch(r) {
+		case '=', '<', '>', '-', '+', '*', '~', '&', '|', '!', '?', '@', '#', '^': return true
+	}
+	return false
+}
+
+// The glyphs of the standard operators and quantifiers are also accepted as
+// single-character identifiers, and read as the names they stand for.  A '"'
+// starts a quoted identifier.
+func (sr *StandardReader) IdentifierStart(r rune) bool {
+	if _, ok := GlyphName(r); ok {
+		return true
+	}
+	return r == '"' || isSymbolChar(r) || isIdentifierStart(r)
+}
+
+func (sr *StandardReader) IdentifierPart(r rune) bool {
+	return isIdentifierPart(r)
+}
+
+// QuoteIdentifier returns name as the standard reader will read it back:
+// unchanged if it is a single identifier, or else quoted and escaped.
+func QuoteIdentifier(name string) string {
+	if !plainIdentifier(name) {
+		return strconv.Quote(name)
+	}
+	return name
+}
+
+func plainIdentifier(name string) bool {
+	first, k := utf8.DecodeRuneInString(name)
+	if name == "" || !isSymbolChar(first) && !isIdentifierStart(first) {
+		return false
+	}
+	for _, r := range name[k:] {
+		if isSymbolChar(first) && !isSymbolChar(r) || !isSymbolChar(first) && !isIdentifierPart(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (sr *StandardReader) ErrorExpected(in *bufio.Reader, expected ...string) {
+	start := sr.cur
+	var found []rune
+	if c, err := sr.readRune(in); err == nil {
+		found = append(found, c)
+		if sr.Chain.IdentifierStart(c) {
+			first := c
+			for {
+				c, err = sr.readRune(in)
+				if err != nil {
+					break
+				}
+				if !sr.identifierContinues(first, c) {
+					sr.unreadRune(in)
+					break
+				}
+				found = append(found, c)
+			}
+		}
+	}
+	sr.Fail(&ParseError{Start: start, End: sr.cur, Expected: expected, Found: string(found)})
+}
+
+func (sr *StandardReader) PeekIdentifierStart(in *bufio.Reader) bool {
+	c, _, err := in.ReadRune()
+	if err != nil {
+		return false
+	}
+	in.UnreadRune()
+	return sr.Chain.IdentifierStart(c)
+}
+
+func (sr *StandardReader) PeekTermType(in *bufio.Reader) ParticleType {
+	sr.NextWS(in)
+	if sr.TestPeek(in, '$') {
+		return VARIABLE
+	}
 	if sr.TestPeek(in, '{') {
-		fmt.Println("PE")
-		return sr.ReadParticle(source, PREDICATE_EXPRESSION, in)
+		return PREDICATE_COMPREHENSION
+	}
+	if !sr.PeekIdentifierStart(in) {
+		sr.ErrorExpected(in, "$", "{", "identifier")
+	}
+	id := sr.NextIdentifier(in)
+	sr.PushIdentifier(id, sr.tokStart)
+	sr.NextWS(in)
+	if sr.TestPeek(in, '(') {
+		return FUNCTION_EXPRESSION
+	}
+	if sr.TestPeek(in, '$') {
+		return QUANTIFIED_TERM
 	}
-	fmt.Println("AP")
-	return sr.ReadParticle(source, ATOMIC_PREDICATE, in)
-}
\ No newline at end of file
+	sr.ErrorExpected(in, "(", "$")
+	return NAME
+}
+
+func (sr *StandardReader) PeekPredicateType(in *bufio.Reader) ParticleType {
+	sr.NextWS(in)
+	if sr.TestPeek(in, '{') {
+		return PREDICATE_EXPRESSION
+	}
+	if !sr.PeekIdentifierStart(in) {
+		sr.ErrorExpected(in, "{", "identifier")
+	}
+	id := sr.NextIdentifier(in)
+	sr.PushIdentifier(id, sr.tokStart)
+	sr.NextWS(in)
+	if sr.TestPeek(in, '[') {
+		return ATOMIC_PREDICATE
+	}
+	if sr.TestPeek(in, '$') {
+		return QUANTIFIED_PREDICATE
+	}
+	sr.ErrorExpected(in, "[", "$")
+	return NAME
+}
+
+func (sr *StandardReader) ReadTerm(source ParticleSource, in *bufio.Reader) (rp Particle, re error) {
+	defer sr.recoverError(&rp, &re)
+	sr.bind(source, in)
+	ptype := sr.PeekTermType(in)
+	return sr.ReadParticle(source, ptype, in)
+}
+
+func (sr *StandardReader) ReadPredicate(source ParticleSource, in *bufio.Reader) (rp Particle, re error) {
+	defer sr.recoverError(&rp, &re)
+	sr.bind(source, in)
+	ptype := sr.PeekPredicateType(in)
+	return sr.ReadParticle(source, ptype, in)
+}
+
+// SkipFormula discards input up to the next top-level formula boundary: a
+// newline or '.' outside any brackets opened by the current formula, or a
+// blank line.
+func (sr *StandardReader) SkipFormula(in *bufio.Reader) {
+	depth := sr.depth
+	blank := false
+	for {
+		c, err := sr.readRune(in)
+		if err != nil {
+			break
+		}
+		if c == '\n' {
+			if depth <= 0 || blank {
+				break
+			}
+			blank = true
+			continue
+		}
+		if c == '.' && depth <= 0 {
+			break
+		}
+		if !unicode.IsSpace(c) {
+			blank = false
+		}
+		switch(c) {
+			case '(', '[', '{': depth += 1
+			case ')', ']', '}': depth -= 1
+		}
+	}
+	sr.reset()
+}
+
+// NextSeparator reads the end of a top-level formula: a newline, a '.'
+// terminator, or the end of input, optionally preceded by spaces and a
+// comment.  A newline already read after the formula's last token counts.
+func (sr *StandardReader) NextSeparator(in *bufio.Reader) *ParseError {
+	if sr.newline {
+		return nil
+	}
+	for {
+		c, err := sr.readRune(in)
+		if err != nil || c == '\n' || c == '.' {
+			return nil
+		}
+		if sr.CommentStart != 0 && c == sr.CommentStart {
+			sr.unreadRune(in)
+			sr.NextWS(in)
+			return nil
+		}
+		if !unicode.IsSpace(c) {
+			sr.unreadRune(in)
+			return &ParseError{Start: sr.cur, End: sr.cur, Expected: []string{"newline", "."}, Found: string(c)}
+		}
+	}
+}
+
+// Particles returns an iterator over the predicates read from r, which must
+// be separated by newlines or '.' terminators.  A malformed predicate is
+// yielded as a nil particle with its *ParseError, and reading resumes after
+// the next formula boundary.  Nothing is retained between particles beyond
+// the read buffer (and Spans, if set), so inputs of any length can be read
+// in bounded memory.
+func (sr *StandardReader) Particles(source ParticleSource, r io.Reader) iter.Seq2[Particle, error] {
+	return func(yield func(Particle, error) bool) {
+		in, ok := r.(*bufio.Reader)
+		if !ok {
+			in = bufio.NewReader(r)
+		}
+		sr.bind(source, in)
+		for {
+			sr.NextWS(in)
+			if _, err := in.Peek(1); err != nil {
+				if err != io.EOF {
+					yield(nil, err)
+				}
+				return
+			}
+			sr.depth = 0
+			p, err := sr.Chain.ReadPredicate(source, in)
+			if err != nil {
+				if !yield(nil, err) {
+					return
+				}
+				sr.SkipFormula(in)
+				continue
+			}
+			if !yield(p, nil) {
+				return
+			}
+			if pe := sr.NextSeparator(in); pe != nil {
+				if !yield(nil, pe) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ReadAll reads predicates from in until end of input.  A malformed formula
+// does not end the read; its error is collected and reading resumes after
+// the next top-level formula boundary (see Particles).
+func (sr *StandardReader) ReadAll(source ParticleSource, in *bufio.Reader) ([]Particle, []*ParseError) {
+	var ps []Particle
+	var errs []*ParseError
+	for p, err := range sr.Particles(source, in) {
+		if err != nil {
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				pe = &ParseError{Start: sr.cur, End: sr.cur, Msg: err.Error()}
+			}
+			errs = append(errs, pe)
+			continue
+		}
+		ps = append(ps, p)
+	}
+	return ps, errs
+}
@@ -1,8 +1,11 @@
 package logic
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"bufio"
 	"testing"
 )
@@ -53,7 +56,7 @@ func TestIO(t *testing.T) {
 	wout := GetStandardWriter()
 	wout.Write(pred, out)
 	fmt.Println()
-	expected := "A$x:{->:Foo[$x],=[$x,$y]}"
+	expected := "A$x:{->:Foo[$x],=[$x,$y]}:"
 	var rin LogicReader
 	rin = GetStandardReader()
 	in := bufio.NewReader(StringReader(expected))
@@ -64,4 +67,380 @@ func TestIO(t *testing.T) {
 		wout.Write(p, out)
 		fmt.Println()
 	}
-}
\ No newline at end of file
+}
+func TestReadTerm(t *testing.T) {
+	source := CreateBasicParticleSource()
+	varX := source.GetVariableNamed("x")
+	varY := source.GetVariableNamed("y")
+	funcF := source.GetFunctionName("f")
+	funcG := source.GetFunctionName("g")
+	predP := source.GetPredicateName("P")
+	predQ := source.GetPredicateName("Q")
+	opAnd := source.GetOperator("and")
+	qIota := source.GetQuantifier("I")
+	terms := []Particle{
+		varX,
+		source.GetFunctionExpression(funcF),
+		source.GetFunctionExpression(funcF, varX, source.GetFunctionExpression(funcG, varY)),
+		source.GetPredicateComprehension(opAnd, 
+			source.GetAtomicPredicate(predP, varX),
+			source.GetAtomicPredicate(predQ)),
+		source.GetQuantifiedTerm(qIota, varX, source.GetAtomicPredicate(predP, varX, varY)),
+		source.GetFunctionExpression(funcG, 
+			source.GetQuantifiedTerm(qIota, varY, 
+				source.GetPredicateExpression(opAnd, 
+					source.GetAtomicPredicate(predQ, varY),
+					source.GetAtomicPredicate(predP, source.GetFunctionExpression(funcF, varY))))),
+	}
+	wout := GetStandardWriter()
+	for _, term := range terms {
+		var buf bytes.Buffer
+		wout.Write(term, &buf)
+		rin := GetStandardReader()
+		p, err := rin.ReadTerm(source, bufio.NewReader(StringReader(buf.String())))
+		if err != nil {
+			t.Errorf("%s: %s", buf.String(), err.Error())
+			continue
+		}
+		if !p.Equals(term) {
+			var out bytes.Buffer
+			wout.Write(p, &out)
+			t.Errorf("read '%s' as '%s'", buf.String(), out.String())
+		}
+	}
+	for _, name := range []Particle{varX.NameParticle(), funcF, predP, opAnd, qIota} {
+		var buf bytes.Buffer
+		wout.Write(name, &buf)
+		rin := GetStandardReader()
+		p, err := rin.ReadParticle(source, name.Type(), bufio.NewReader(StringReader(buf.String())))
+		if err != nil {
+			t.Errorf("%s: %s", buf.String(), err.Error())
+		} else if !p.Equals(name) {
+			t.Errorf("read '%s' as %s '%s'", buf.String(), p.Type().String(), p.(Name).String())
+		}
+	}
+	spaced := "f ( $x , g( $y ) )"
+	rin := GetStandardReader()
+	if p, err := rin.ReadTerm(source, bufio.NewReader(StringReader(spaced))); err != nil {
+		t.Error(err)
+	} else if !p.Equals(terms[2]) {
+		t.Errorf("read '%s' incorrectly", spaced)
+	}
+	rin = GetStandardReader()
+	if _, err := rin.ReadTerm(source, bufio.NewReader(StringReader("f[$x]"))); err == nil {
+		t.Error("expected error reading predicate as term")
+	}
+}
+
+func TestReadPredicate(t *testing.T) {
+	source := CreateBasicParticleSource()
+	varX := source.GetVariableNamed("x")
+	varY := source.GetVariableNamed("y")
+	predP := source.GetPredicateName("P")
+	predQ := source.GetPredicateName("Q")
+	opImpl := source.GetOperator("implies")
+	qAll := source.GetQuantifier("A")
+	qEx := source.GetQuantifier("E")
+	preds := []Particle{
+		source.GetAtomicPredicate(predQ),
+		source.GetAtomicPredicate(predP, varX, varY),
+		source.GetPredicateExpression(opImpl, 
+			source.GetAtomicPredicate(predP, varX),
+			source.GetAtomicPredicate(predQ, varY)),
+		source.GetQuantifiedPredicate(qAll, varX, source.GetAtomicPredicate(predP, varX)),
+		source.GetQuantifiedPredicate(qAll, varX, 
+			source.GetQuantifiedPredicate(qEx, varY,
+				source.GetPredicateExpression(opImpl, 
+					source.GetAtomicPredicate(predP, varX),
+					source.GetQuantifiedPredicate(qAll, varX, source.GetAtomicPredicate(predQ, varX, varY))))),
+	}
+	wout := GetStandardWriter()
+	for _, pred := range preds {
+		var buf bytes.Buffer
+		wout.Write(pred, &buf)
+		rin := GetStandardReader()
+		p, err := rin.ReadPredicate(source, bufio.NewReader(StringReader(buf.String())))
+		if err != nil {
+			t.Errorf("%s: %s", buf.String(), err.Error())
+			continue
+		}
+		if !p.Equals(pred) {
+			var out bytes.Buffer
+			wout.Write(p, &out)
+			t.Errorf("read '%s' as '%s'", buf.String(), out.String())
+		}
+	}
+	rin := GetStandardReader()
+	_, err := rin.ReadPredicate(source, bufio.NewReader(StringReader("P($x)")))
+	if err == nil || !strings.Contains(err.Error(), "expected '[' or '$' (found '(')") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	source := CreateBasicParticleSource()
+	cases := []struct {
+		src string
+		start Position
+		end Position
+		expected []string
+		found string
+	}{
+		{"P($x)", Position{1,2,1}, Position{1,3,2}, []string{"[", "$"}, "("},
+		{"{and:\n  P[$x],\n  Qué[$x;]}", Position{3,9,24}, Position{3,10,25}, []string{","}, ";"},
+		{"A$x:P[$x]", Position{1,10,9}, Position{1,10,9}, []string{":"}, ""},
+		{"  \n  P[$x, f]", Position{2,10,12}, Position{2,11,13}, []string{"(", "$"}, "]"},
+	}
+	for _, c := range cases {
+		rin := GetStandardReader()
+		_, err := rin.ReadPredicate(source, bufio.NewReader(StringReader(c.src)))
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Errorf("%q: expected ParseError, got %v", c.src, err)
+			continue
+		}
+		if pe.Start != c.start || pe.End != c.end {
+			t.Errorf("%q: span %v-%v, expected %v-%v", c.src, pe.Start, pe.End, c.start, c.end)
+		}
+		if strings.Join(pe.Expected, " ") != strings.Join(c.expected, " ") || pe.Found != c.found {
+			t.Errorf("%q: %s", c.src, pe.Error())
+		}
+	}
+}
+
+func TestReadSpans(t *testing.T) {
+	source := CreateBasicParticleSource()
+	src := "A$x:{and: P[$x],\n   Q[f( $x ), $y]}:"
+	rin := GetStandardReader()
+	rin.Spans = NewSpanTable()
+	p, err := rin.ReadPredicate(source, bufio.NewReader(StringReader(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := func(p Particle) string {
+		span, ok := rin.Spans.Span(p)
+		if !ok {
+			return "<none>"
+		}
+		return src[span.Start.Offset:span.End.Offset]
+	}
+	body := p.(QuantifiedParticle).Argument().(TupleParticle)
+	q := body.Argument(1).(TupleParticle)
+	checks := []struct {
+		p Particle
+		text string
+	}{
+		{p, src},
+		{p.(QuantifiedParticle).Variable(), "$x"},
+		{body, src[4:len(src)-1]},
+		{body.Head(), "and"},
+		{body.Argument(0), "P[$x]"},
+		{q, "Q[f( $x ), $y]"},
+		{q.Head(), "Q"},
+		{q.Argument(0), "f( $x )"},
+		{q.Argument(1), "$y"},
+	}
+	for _, c := range checks {
+		if text(c.p) != c.text {
+			t.Errorf("span of %s is '%s', expected '%s'", c.p.Type().String(), text(c.p), c.text)
+		}
+	}
+	span, _ := rin.Spans.Span(q)
+	if span.Start != (Position{2, 4, 20}) {
+		t.Errorf("unexpected start %#v", span.Start)
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	source := CreateBasicParticleSource()
+	src := strings.Join([]string{
+		"P[$x]",
+		"{and: Q[$x],",
+		"      R[$x; $y]}  Q[] R[$z]",
+		"A$x:{or:",
+		"  P[$x],",
+		"  Q[$x]}:",
+		"Q[f($x]",
+		"",
+		"S[]",
+		"{or: P[$x] Q[]}",
+		"R[$y]",
+	}, "\n")
+	rin := GetStandardReader()
+	ps, errs := rin.ReadAll(source, bufio.NewReader(StringReader(src)))
+	var names []string
+	for _, p := range ps {
+		var buf bytes.Buffer
+		GetStandardWriter().Write(p, &buf)
+		names = append(names, buf.String())
+	}
+	expected := "P[$x] A$x:{or:P[$x],Q[$x]}: S[] R[$y]"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("read %v, expected %s", names, expected)
+	}
+	var lines []int
+	for _, e := range errs {
+		lines = append(lines, e.Start.Line)
+	}
+	if fmt.Sprint(lines) != "[3 7 10]" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
+
+type formulaStream struct {
+	n int
+	buf []byte
+}
+
+func (fs *formulaStream) Read(p []byte) (int, error) {
+	if len(fs.buf) == 0 {
+		if fs.n == 0 {
+			return 0, io.EOF
+		}
+		fs.n -= 1
+		fs.buf = []byte(fmt.Sprintf("A$x:{and:P[$x],Q[f%d($x)]}:\n", fs.n))
+	}
+	k := copy(p, fs.buf)
+	fs.buf = fs.buf[k:]
+	return k, nil
+}
+
+func TestParticles(t *testing.T) {
+	source := CreateBasicParticleSource()
+	src := "P[$x]. Q[$y].\n  R[]  \nS[] T[]\nU[$x;]\nV[]"
+	var read []string
+	var errs []string
+	for p, err := range GetStandardReader().Particles(source, StringReader(src)) {
+		if err != nil {
+			errs = append(errs, err.(*ParseError).Start.String())
+			continue
+		}
+		read = append(read, p.(TupleParticle).Head().String())
+	}
+	if strings.Join(read, " ") != "P Q R S T V" || strings.Join(errs, " ") != "3:5 4:5" {
+		t.Errorf("read %v with errors %v", read, errs)
+	}
+
+	count := 0
+	for p, err := range GetStandardReader().Particles(source, &formulaStream{n: 20000}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Type() != QUANTIFIED_PREDICATE {
+			t.Fatalf("read %s", p.Type().String())
+		}
+		count += 1
+	}
+	if count != 20000 {
+		t.Errorf("read %d particles", count)
+	}
+	count = 0
+	for range GetStandardReader().Particles(source, &formulaStream{n: 20000}) {
+		count += 1
+		if count == 10 {
+			break
+		}
+	}
+	if count != 10 {
+		t.Errorf("iteration did not stop")
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x := source.GetVariableNamed("straße")
+	y := source.GetVariableNamed("y٣")
+	pred := source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), x,
+		source.GetPredicateExpression(source.GetOperator(OP_IMPLIES),
+			source.GetAtomicPredicate(source.GetPredicateName("Größe"), x),
+			source.GetPredicateExpression(source.GetOperator(OP_NOT),
+				source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_EXISTS), y,
+					source.GetAtomicPredicate(source.GetPredicateName("Ιδ_é"), x, y)))))
+	wout := GetStandardWriter()
+	wout.Unicode = true
+	var buf bytes.Buffer
+	wout.Write(pred, &buf)
+	expected := "∀$straße:{→:Größe[$straße],{¬:∃$y٣:Ιδ_é[$straße,$y٣]:}}:"
+	if buf.String() != expected {
+		t.Errorf("wrote %s", buf.String())
+	}
+	for _, src := range []string{expected, strings.NewReplacer("∀", "A", "→", "->", "¬", "~", "∃", "E").Replace(expected)} {
+		p, err := GetStandardReader().ReadPredicate(source, bufio.NewReader(StringReader(src)))
+		if err != nil {
+			t.Errorf("%s: %s", src, err.Error())
+		} else if !p.Equals(pred) {
+			t.Errorf("read %s incorrectly", src)
+		}
+	}
+	op := source.GetOperator(OP_AND)
+	buf.Reset()
+	wout.Write(op, &buf)
+	p, err := GetStandardReader().ReadParticle(source, OPERATOR, bufio.NewReader(StringReader(buf.String())))
+	if buf.String() != "'op:∧'" || err != nil || !p.Equals(op) {
+		t.Errorf("operator name %s did not round trip", buf.String())
+	}
+}
+
+func TestQuotedIdentifiers(t *testing.T) {
+	source := CreateBasicParticleSource()
+	cases := map[string]Particle{
+		"$\"two words\"": source.GetVariableNamed("two words"),
+		"$\"\"": source.GetVariableNamed(""),
+		"'op:\"∧\"'": source.GetOperator("∧"),
+		"'func:\"f(x)\"'": source.GetFunctionName("f(x)"),
+		"\"tab\\there\"($x)": source.GetFunctionExpression(source.GetFunctionName("tab\there"), source.GetVariableNamed("x")),
+		"<=[$x,$y]": source.GetAtomicPredicate(source.GetPredicateName("<="), source.GetVariableNamed("x"), source.GetVariableNamed("y")),
+		"{\"→\":\"P 1\"[]}": source.GetPredicateExpression(source.GetOperator("→"), source.GetAtomicPredicate(source.GetPredicateName("P 1"))),
+	}
+	wout := GetStandardWriter()
+	for text, p := range cases {
+		var buf bytes.Buffer
+		wout.Write(p, &buf)
+		if buf.String() != text {
+			t.Errorf("wrote %s, expected %s", buf.String(), text)
+		}
+		rp, err := GetStandardReader().ReadParticle(source, p.Type(), bufio.NewReader(StringReader(text)))
+		if err != nil {
+			t.Errorf("%s: %s", text, err.Error())
+		} else if !rp.Equals(p) {
+			t.Errorf("read %s incorrectly", text)
+		}
+	}
+}
+
+func FuzzNameRoundTrip(f *testing.F) {
+	for _, seed := range []string{"x", "->", "=", "two words", "", "\"", "'", "\\", "∀", "x->y", "f/2", "\n", "\xff"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		source := CreateBasicParticleSource()
+		x := source.GetVariableNamed(name)
+		particles := []Particle{
+			x,
+			source.GetFunctionName(name),
+			source.GetOperator(name),
+			source.GetQuantifier(name),
+			source.GetFunctionExpression(source.GetFunctionName(name), x),
+			source.GetQuantifiedPredicate(source.GetQuantifier(name), x,
+				source.GetPredicateExpression(source.GetOperator(name),
+					source.GetAtomicPredicate(source.GetPredicateName(name), x))),
+		}
+		for _, unicode := range []bool{false, true} {
+			wout := GetStandardWriter()
+			wout.Unicode = unicode
+			for _, p := range particles {
+				var buf bytes.Buffer
+				if err := wout.Write(p, &buf); err != nil {
+					t.Fatal(err)
+				}
+				rp, err := GetStandardReader().ReadParticle(source, p.Type(), bufio.NewReader(bytes.NewReader(buf.Bytes())))
+				if err != nil {
+					t.Fatalf("%q: %s", buf.String(), err.Error())
+				}
+				if !rp.Equals(p) {
+					t.Fatalf("%q did not round trip", buf.String())
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// JSONParticle is the JSON form of a particle.  Every node has a "type",
+// the ParticleType.String() value of the particle, and the rest depends on
+// the type:
+//
+//	variable and names   {"type": "variable", "name": "x"}
+//	tuples               {"type": "function-expression", "head": "f", "args": [...]}
+//	quantified           {"type": "quantified-predicate", "head": "A", "args": [variable, body]}
+//
+// The head of a tuple is the name of its function, predicate or operator,
+// and args holds its arguments, and is omitted when there are none.  The
+// head of a quantified particle is its quantifier.
+type JSONParticle struct {
+	Type string `json:"type"`
+	Name *string `json:"name,omitempty"`
+	Head *string `json:"head,omitempty"`
+	Args []*JSONParticle `json:"args,omitempty"`
+}
+
+// NewJSONParticle returns the JSON tree for p.
+func NewJSONParticle(p Particle) *JSONParticle {
+	jp := &JSONParticle{Type: p.Type().String()}
+	switch(p.Type()) {
+		case VARIABLE: {
+			name := p.(NamedParticle).String()
+			jp.Name = &name
+		}
+		case FUNCTION_EXPRESSION, ATOMIC_PREDICATE, PREDICATE_COMPREHENSION, PREDICATE_EXPRESSION: {
+			tp := p.(TupleParticle)
+			head := tp.Head().String()
+			jp.Head = &head
+			for _, arg := range tp.Arguments() {
+				jp.Args = append(jp.Args, NewJSONParticle(arg))
+			}
+		}
+		case QUANTIFIED_TERM, QUANTIFIED_PREDICATE: {
+			qp := p.(QuantifiedParticle)
+			head := qp.Quantifier().String()
+			jp.Head = &head
+			jp.Args = []*JSONParticle{NewJSONParticle(qp.Variable()), NewJSONParticle(qp.Argument())}
+		}
+		default: {
+			name := p.(Name).String()
+			jp.Name = &name
+		}
+	}
+	return jp
+}
+
+// MarshalParticle encodes p as JSON, without escaping HTML characters, which
+// are common in operator names.
+func MarshalParticle(p Particle) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(NewJSONParticle(p)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// UnmarshalParticle decodes the JSON form of a particle, building it with
+// source.
+func UnmarshalParticle(source ParticleSource, data []byte) (Particle, error) {
+	var jp JSONParticle
+	if err := json.Unmarshal(data, &jp); err != nil {
+		return nil, err
+	}
+	return jp.Particle(source)
+}
+
+// Particle builds the particle jp describes with source.  Each node is checked
+// before it is built, and errors give the path of the offending node.
+func (jp *JSONParticle) Particle(source ParticleSource) (Particle, error) {
+	return jp.particle(source, "particle")
+}
+
+func (jp *JSONParticle) fail(path string, msg string) error {
+	return errors.New(fmt.Sprintf("%s: %s", path, msg))
+}
+
+func (jp *JSONParticle) particle(source ParticleSource, path string) (Particle, error) {
+	if jp == nil {
+		return nil, jp.fail(path, "missing particle")
+	}
+	ptype, err := ParseParticleType(jp.Type)
+	if err != nil {
+		return nil, jp.fail(path, err.Error())
+	}
+	if ptype == NAME {
+		return nil, jp.fail(path, "untyped name")
+	}
+	args := make([]Particle, len(jp.Args))
+	for i, arg := range jp.Args {
+		if args[i], err = arg.particle(source, fmt.Sprintf("%s.args[%d]", path, i)); err != nil {
+			return nil, err
+		}
+	}
+	switch(ptype) {
+		case VARIABLE, VARIABLE_NAME, FUNCTION_NAME, PREDICATE_NAME, OPERATOR, QUANTIFIER: {
+			if jp.Name == nil || jp.Head != nil || len(jp.Args) > 0 {
+				return nil, jp.fail(path, fmt.Sprintf("%s needs a name and nothing else", jp.Type))
+			}
+			if ptype == VARIABLE {
+				return source.GetVariableNamed(*jp.Name), nil
+			}
+			return source.GetName(ptype, *jp.Name), nil
+		}
+		case QUANTIFIED_TERM, QUANTIFIED_PREDICATE: {
+			if jp.Head == nil || jp.Name != nil || len(args) != 2 {
+				return nil, jp.fail(path, fmt.Sprintf("%s needs a head and two args", jp.Type))
+			}
+			v, ok := args[0].(NamedParticle)
+			if !ok || v.Type() != VARIABLE {
+				return nil, jp.fail(path+".args[0]", "expected variable")
+			}
+			if !args[1].Predicate() {
+				return nil, jp.fail(path+".args[1]", "expected predicate")
+			}
+			q := source.GetQuantifier(*jp.Head)
+			if ptype == QUANTIFIED_TERM {
+				return source.GetQuantifiedTerm(q, v, args[1]), nil
+			}
+			return source.GetQuantifiedPredicate(q, v, args[1]), nil
+		}
+	}
+	if jp.Head == nil || jp.Name != nil {
+		return nil, jp.fail(path, fmt.Sprintf("%s needs a head", jp.Type))
+	}
+	terms := ptype == FUNCTION_EXPRESSION || ptype == ATOMIC_PREDICATE
+	for i, arg := range args {
+		if terms && !arg.Term() {
+			return nil, jp.fail(fmt.Sprintf("%s.args[%d]", path, i), "expected term")
+		}
+		if !terms && !arg.Predicate() {
+			return nil, jp.fail(fmt.Sprintf("%s.args[%d]", path, i), "expected predicate")
+		}
+	}
+	var head Name
+	switch(ptype) {
+		case FUNCTION_EXPRESSION: head = source.GetFunctionName(*jp.Head)
+		case ATOMIC_PREDICATE: head = source.GetPredicateName(*jp.Head)
+		default: head = source.GetOperator(*jp.Head)
+	}
+	return source.GetTuple(ptype, head, args...), nil
+}
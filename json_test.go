@@ -0,0 +1,68 @@
+package logic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseParticleType(t *testing.T) {
+	for pt := NAME; pt <= QUANTIFIED_PREDICATE; pt++ {
+		if parsed, err := ParseParticleType(pt.String()); err != nil || parsed != pt {
+			t.Errorf("%s did not round trip", pt.String())
+		}
+	}
+	if _, err := ParseParticleType("<unknown>"); err == nil {
+		t.Error("expected error for unknown type")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x := source.GetVariableNamed("x")
+	pred := source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), x,
+		source.GetPredicateExpression(source.GetOperator(OP_IMPLIES),
+			source.GetAtomicPredicate(source.GetPredicateName("P"), source.GetFunctionExpression(source.GetFunctionName("c"))),
+			source.GetAtomicPredicate(source.GetPredicateName(PRED_EQUALS), x,
+				source.GetQuantifiedTerm(source.GetQuantifier("I"), x,
+					source.GetAtomicPredicate(source.GetPredicateName("Q"),
+						source.GetPredicateComprehension(source.GetOperator(OP_AND)))))))
+	data, err := MarshalParticle(pred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"type":"quantified-predicate","head":"A","args":[{"type":"variable","name":"x"},` +
+		`{"type":"predicate-expression","head":"->","args":[` +
+		`{"type":"atomic-predicate","head":"P","args":[{"type":"function-expression","head":"c"}]},` +
+		`{"type":"atomic-predicate","head":"=","args":[{"type":"variable","name":"x"},` +
+		`{"type":"quantified-term","head":"I","args":[{"type":"variable","name":"x"},` +
+		`{"type":"atomic-predicate","head":"Q","args":[{"type":"predicate-comprehension","head":"&"}]}]}]}]}]}`
+	if string(data) != expected {
+		t.Errorf("marshalled %s", string(data))
+	}
+	p, err := UnmarshalParticle(CreateBasicParticleSource(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Equals(pred) {
+		t.Error("particle did not round trip")
+	}
+	for _, name := range []Particle{source.GetVariableName(""), source.GetOperator(OP_NOT), source.GetQuantifier("E")} {
+		data, _ := MarshalParticle(name)
+		if p, err := UnmarshalParticle(source, data); err != nil || !p.Equals(name) {
+			t.Errorf("%s did not round trip", string(data))
+		}
+	}
+	for data, msg := range map[string]string{
+		`{"type":"name","name":"x"}`: "particle: untyped name",
+		`{"type":"variable"}`: "particle: variable needs a name",
+		`{"type":"atomic-predicate","head":"P","args":[{"type":"atomic-predicate","head":"Q"}]}`: "particle.args[0]: expected term",
+		`{"type":"quantified-term","head":"I","args":[{"type":"function-expression","head":"c"},{"type":"atomic-predicate","head":"Q"}]}`: "particle.args[0]: expected variable",
+		`{"type":"predicate-expression","head":"&","args":[{"type":"vari"}]}`: "particle.args[0]: unknown particle type 'vari'",
+		`{"type":"predicate-expression","head":"&","args":[null]}`: "particle.args[0]: missing particle",
+		`[1]`: "json:",
+	} {
+		if _, err := UnmarshalParticle(source, []byte(data)); err == nil || !strings.HasPrefix(err.Error(), msg) {
+			t.Errorf("%s: got error %v", data, err)
+		}
+	}
+}
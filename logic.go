@@ -1,6 +1,10 @@
 package logic
 
-import "github.com/dtromb/logic/hash"
+import (
+	"errors"
+	"fmt"
+	"github.com/dtromb/logic/hash"
+)
 
 type ParticleType int
 const (
@@ -37,6 +41,54 @@ func (pt ParticleType) String() string {
 	return "<unknown>"
 }
 
+// ParseParticleType returns the type whose String() value is s.
+func ParseParticleType(s string) (ParticleType, error) {
+	for pt := NAME; pt <= QUANTIFIED_PREDICATE; pt++ {
+		if pt.String() == s {
+			return pt, nil
+		}
+	}
+	return NAME, errors.New(fmt.Sprintf("unknown particle type '%s'", s))
+}
+
+// Names given special notation by the readers and writers in this package.
+const (
+	OP_NOT = "~"
+	OP_AND = "&"
+	OP_OR = "|"
+	OP_IMPLIES = "->"
+	OP_IFF = "<->"
+	QUANT_FORALL = "A"
+	QUANT_EXISTS = "E"
+	PRED_EQUALS = "="
+)
+
+var standardGlyphs = map[string]rune{
+	OP_NOT: '¬',
+	OP_AND: '∧',
+	OP_OR: '∨',
+	OP_IMPLIES: '→',
+	OP_IFF: '↔',
+	QUANT_FORALL: '∀',
+	QUANT_EXISTS: '∃',
+}
+
+// Glyph returns the Unicode symbol for a standard operator or quantifier.
+func Glyph(name string) (rune, bool) {
+	g, ok := standardGlyphs[name]
+	return g, ok
+}
+
+// GlyphName returns the standard operator or quantifier written as r.
+func GlyphName(r rune) (string, bool) {
+	for name, g := range standardGlyphs {
+		if g == r {
+			return name, true
+		}
+	}
+	return "", false
+}
+
 type ParticleSource interface {
 	Get(ptype ParticleType, parts ...Particle) Particle
 	GetName(nameType ParticleType, name string) Name
@@ -102,3 +154,15 @@ func HashParticleArray(arr []Particle) uint64 {
 	}
 	return h
 }
+
+// CopyParticle rebuilds p with source.
+func CopyParticle(source ParticleSource, p Particle) Particle {
+	if p.Name() {
+		return source.GetName(p.Type(), p.(Name).String())
+	}
+	parts := p.Parts()
+	for i, part := range parts {
+		parts[i] = CopyParticle(source, part)
+	}
+	return source.Get(p.Type(), parts...)
+}
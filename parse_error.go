@@ -0,0 +1,60 @@
+package logic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Position is a location in reader input.  Line and Col are 1-based, Col
+// counting runes; Offset is the 0-based byte offset.
+type Position struct {
+	Line int
+	Col int
+	Offset int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// ParseError is the error returned by readers for malformed input.  Start and
+// End delimit the offending text; Expected lists the alternatives the reader
+// would have accepted there.
+type ParseError struct {
+	Start Position
+	End Position
+	Expected []string
+	Found string
+	Msg string
+	File string
+}
+
+func (pe *ParseError) Description() string {
+	var desc string
+	if pe.Msg != "" {
+		desc = pe.Msg
+	} else if len(pe.Expected) > 0 {
+		alts := make([]string, len(pe.Expected))
+		for i, e := range pe.Expected {
+			alts[i] = fmt.Sprintf("'%s'", e)
+		}
+		desc = fmt.Sprintf("expected %s", strings.Join(alts, " or "))
+	} else {
+		desc = "syntax error"
+	}
+	if len(pe.Expected) > 0 {
+		if pe.Found == "" {
+			desc += " (found end of input)"
+		} else {
+			desc += fmt.Sprintf(" (found '%s')", pe.Found)
+		}
+	}
+	return desc
+}
+
+func (pe *ParseError) Error() string {
+	if pe.File != "" {
+		return fmt.Sprintf("%s:%s: %s", pe.File, pe.Start.String(), pe.Description())
+	}
+	return fmt.Sprintf("%s at %s (pos=%d)", pe.Description(), pe.Start.String(), pe.Start.Offset)
+}
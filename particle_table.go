@@ -0,0 +1,34 @@
+package logic
+
+// particleTable numbers distinct particles, identifying them by Hash() and
+// Equals.
+type particleTable struct {
+	buckets map[uint64][]int
+	particles []Particle
+}
+
+func newParticleTable() *particleTable {
+	return &particleTable{buckets: make(map[uint64][]int)}
+}
+
+// index returns the number of a particle equal to p, if there is one.
+func (pt *particleTable) index(p Particle) (int, bool) {
+	for _, i := range pt.buckets[p.Hash()] {
+		if pt.particles[i].Equals(p) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// add numbers p, which must not be in the table already.
+func (pt *particleTable) add(p Particle) int {
+	i := len(pt.particles)
+	pt.particles = append(pt.particles, p)
+	pt.buckets[p.Hash()] = append(pt.buckets[p.Hash()], i)
+	return i
+}
+
+func (pt *particleTable) len() int {
+	return len(pt.particles)
+}
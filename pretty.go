@@ -0,0 +1,229 @@
+package logic
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// PrettyWriter writes particles in the standard syntax, breaking lines to
+// fit within Width columns where it can.  A formula that fits is written on
+// one line exactly as StandardWriter writes it; otherwise the arguments of
+// predicate expressions and comprehensions and the bodies of quantifiers are
+// put on their own lines, nested by Indent, and argument lists of function
+// expressions and atomic predicates are aligned after their opening bracket.
+//
+// The layout follows Wadler's "A prettier printer": particles are first
+// turned into a document of text, possible line breaks and groups, and each
+// group is then written flat if the rest of its line fits, or broken if not.
+type PrettyWriter struct {
+	LogicWriter
+	Standard *StandardWriter
+	Width int
+	Indent int
+}
+
+type docKind int
+const (
+	textDoc docKind = iota
+	lineDoc
+	concatDoc
+	nestDoc
+	alignDoc
+	groupDoc
+)
+
+// A doc is a document to be laid out.  A lineDoc is written as text when
+// flat, and as a newline and indentation when broken.
+type doc struct {
+	kind docKind
+	text string
+	indent int
+	docs []*doc
+}
+
+type docItem struct {
+	indent int
+	flat bool
+	doc *doc
+}
+
+func GetPrettyWriter(width int) *PrettyWriter {
+	return &PrettyWriter{Standard: GetStandardWriter(), Width: width, Indent: 2}
+}
+
+func docText(s string) *doc {
+	return &doc{kind: textDoc, text: s}
+}
+
+// docLine is a break that is written as nothing when flat; the standard
+// syntax needs no space between tokens.
+func docLine() *doc {
+	return &doc{kind: lineDoc}
+}
+
+func docConcat(ds ...*doc) *doc {
+	return &doc{kind: concatDoc, docs: ds}
+}
+
+func docNest(indent int, ds ...*doc) *doc {
+	return &doc{kind: nestDoc, indent: indent, docs: ds}
+}
+
+func docAlign(ds ...*doc) *doc {
+	return &doc{kind: alignDoc, docs: ds}
+}
+
+func docGroup(ds ...*doc) *doc {
+	return &doc{kind: groupDoc, docs: ds}
+}
+
+func (pw *PrettyWriter) Write(p Particle, out io.Writer) error {
+	d, err := pw.doc(p)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	if err := renderDoc(d, pw.Width, w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (pw *PrettyWriter) doc(p Particle) (*doc, error) {
+	switch(p.Type()) {
+		case FUNCTION_EXPRESSION: fallthrough
+		case ATOMIC_PREDICATE: {
+			tp := p.(TupleParticle)
+			open, close := "(", ")"
+			if p.Type() == ATOMIC_PREDICATE {
+				open, close = "[", "]"
+			}
+			args, err := pw.docList(tp.Arguments())
+			if err != nil {
+				return nil, err
+			}
+			return docGroup(docText(QuoteIdentifier(tp.Head().String())+open), docAlign(args), docText(close)), nil
+		}
+		case PREDICATE_COMPREHENSION: fallthrough
+		case PREDICATE_EXPRESSION: {
+			tp := p.(TupleParticle)
+			args, err := pw.docList(tp.Arguments())
+			if err != nil {
+				return nil, err
+			}
+			head := docText("{" + pw.Standard.Symbol(tp.Head()) + PredicateTupleMark(p.Type()))
+			if tp.Arity() == 0 {
+				return docConcat(head, docText("}")), nil
+			}
+			return docGroup(head, docNest(pw.Indent, docLine(), args), docLine(), docText("}")), nil
+		}
+		case QUANTIFIED_TERM: fallthrough
+		case QUANTIFIED_PREDICATE: {
+			qp := p.(QuantifiedParticle)
+			var b strings.Builder
+			b.WriteString(pw.Standard.Symbol(qp.Quantifier()))
+			if err := pw.Standard.Write(qp.Variable(), &b); err != nil {
+				return nil, err
+			}
+			b.WriteString(":")
+			body, err := pw.doc(qp.Argument())
+			if err != nil {
+				return nil, err
+			}
+			return docGroup(docText(b.String()), docNest(pw.Indent, docLine(), body), docText(":")), nil
+		}
+	}
+	var b strings.Builder
+	if err := pw.Standard.Write(p, &b); err != nil {
+		return nil, err
+	}
+	return docText(b.String()), nil
+}
+
+// docList lays out comma-separated arguments, one per line when broken.
+func (pw *PrettyWriter) docList(args []Particle) (*doc, error) {
+	var ds []*doc
+	for i, arg := range args {
+		d, err := pw.doc(arg)
+		if err != nil {
+			return nil, err
+		}
+		if i < len(args)-1 {
+			ds = append(ds, d, docText(","), docLine())
+		} else {
+			ds = append(ds, d)
+		}
+	}
+	return docConcat(ds...), nil
+}
+
+func renderDoc(d *doc, width int, w *bufio.Writer) error {
+	if width <= 0 {
+		return errors.New("pretty printer width must be positive")
+	}
+	stack := []docItem{{doc: d}}
+	col := 0
+	for len(stack) > 0 {
+		it := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch(it.doc.kind) {
+			case textDoc: {
+				w.WriteString(it.doc.text)
+				col += utf8.RuneCountInString(it.doc.text)
+			}
+			case lineDoc: {
+				if !it.flat {
+					w.WriteString("\n")
+					w.WriteString(strings.Repeat(" ", it.indent))
+					col = it.indent
+				}
+			}
+			case groupDoc: {
+				flat := it.flat || fitsDoc(width-col, docItem{it.indent, true, docConcat(it.doc.docs...)}, stack)
+				stack = pushDocs(stack, it.indent, flat, it.doc.docs)
+			}
+			case nestDoc: stack = pushDocs(stack, it.indent+it.doc.indent, it.flat, it.doc.docs)
+			case alignDoc: stack = pushDocs(stack, col, it.flat, it.doc.docs)
+			case concatDoc: stack = pushDocs(stack, it.indent, it.flat, it.doc.docs)
+		}
+	}
+	return nil
+}
+
+func pushDocs(stack []docItem, indent int, flat bool, ds []*doc) []docItem {
+	for i := len(ds)-1; i >= 0; i-- {
+		stack = append(stack, docItem{indent, flat, ds[i]})
+	}
+	return stack
+}
+
+// fitsDoc reports whether item, followed by the rest of the stack, fits in
+// the remaining width up to the first line break outside a flat group.
+func fitsDoc(remaining int, item docItem, rest []docItem) bool {
+	local := []docItem{item}
+	next := len(rest)-1
+	for remaining >= 0 {
+		if len(local) == 0 {
+			if next < 0 {
+				return true
+			}
+			local = append(local, rest[next])
+			next -= 1
+		}
+		it := local[len(local)-1]
+		local = local[:len(local)-1]
+		switch(it.doc.kind) {
+			case textDoc: remaining -= utf8.RuneCountInString(it.doc.text)
+			case lineDoc: {
+				if !it.flat {
+					return true
+				}
+			}
+			default: local = pushDocs(local, it.indent, it.flat, it.doc.docs)
+		}
+	}
+	return false
+}
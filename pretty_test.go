@@ -0,0 +1,92 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type failingWriter struct {
+	n int
+}
+
+func (fw *failingWriter) Write(p []byte) (int, error) {
+	if fw.n <= 0 {
+		return 0, errors.New("write failed")
+	}
+	fw.n -= 1
+	return len(p), nil
+}
+
+func prettyFormula(source ParticleSource) Particle {
+	x := source.GetVariableNamed("x")
+	y := source.GetVariableNamed("y")
+	f := source.GetFunctionName("father")
+	return source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), x,
+		source.GetPredicateExpression(source.GetOperator(OP_IMPLIES),
+			source.GetAtomicPredicate(source.GetPredicateName("Person"), x),
+			source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_EXISTS), y,
+				source.GetPredicateExpression(source.GetOperator(OP_AND),
+					source.GetAtomicPredicate(source.GetPredicateName("="), y, source.GetFunctionExpression(f, x)),
+					source.GetAtomicPredicate(source.GetPredicateName("Ancestor"),
+						source.GetFunctionExpression(f, source.GetFunctionExpression(f, y)), x)))))
+}
+
+func TestPrettyWriter(t *testing.T) {
+	source := CreateBasicParticleSource()
+	pred := prettyFormula(source)
+	var flat, buf bytes.Buffer
+	GetStandardWriter().Write(pred, &flat)
+	if err := GetPrettyWriter(200).Write(pred, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != flat.String() {
+		t.Errorf("wide layout %s differs from standard %s", buf.String(), flat.String())
+	}
+	expected := strings.Join([]string{
+		"A$x:",
+		"  {->:",
+		"    Person[$x],",
+		"    E$y:",
+		"      {&:",
+		"        =[$y,father($x)],",
+		"        Ancestor[father(father($y)),",
+		"                 $x]",
+		"      }:",
+		"  }:",
+	}, "\n")
+	for _, width := range []int{30, 1} {
+		buf.Reset()
+		pw := GetPrettyWriter(width)
+		if err := pw.Write(pred, &buf); err != nil {
+			t.Fatal(err)
+		}
+		if width == 30 && buf.String() != expected {
+			t.Errorf("width %d layout:\n%s", width, buf.String())
+		}
+		p, err := GetStandardReader().ReadPredicate(source, bufio.NewReader(StringReader(buf.String())))
+		if err != nil {
+			t.Errorf("width %d: %s", width, err.Error())
+		} else if !p.Equals(pred) {
+			t.Errorf("width %d layout read incorrectly", width)
+		}
+	}
+	if err := GetPrettyWriter(0).Write(pred, &buf); err == nil {
+		t.Error("expected error for zero width")
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	source := CreateBasicParticleSource()
+	pred := prettyFormula(source)
+	for n := 0; n < 5; n++ {
+		if err := GetStandardWriter().Write(pred, &failingWriter{n: n}); err == nil {
+			t.Errorf("standard writer ignored failure after %d writes", n)
+		}
+	}
+	if err := GetPrettyWriter(20).Write(pred, &failingWriter{}); err == nil {
+		t.Error("pretty writer ignored write failure")
+	}
+}
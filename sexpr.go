@@ -0,0 +1,465 @@
+package logic
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+type sexprKind int
+const (
+	sexprAtom sexprKind = iota
+	sexprQuoted
+	sexprString
+	sexprList
+)
+
+// sexpr is a parsed s-expression.  Quoted atoms are written |like this| and
+// strings "like this", with "" standing for a quote.
+type sexpr struct {
+	kind sexprKind
+	atom string
+	items []*sexpr
+	start Position
+	end Position
+}
+
+func (e *sexpr) isAtom(atom string) bool {
+	return e.kind == sexprAtom && e.atom == atom
+}
+
+// head returns the unquoted atom at the head of a list, if there is one.
+func (e *sexpr) head() string {
+	if e.kind == sexprList && len(e.items) > 0 && e.items[0].kind == sexprAtom {
+		return e.items[0].atom
+	}
+	return ""
+}
+
+func (e *sexpr) String() string {
+	switch(e.kind) {
+		case sexprQuoted: return "|" + e.atom + "|"
+		case sexprString: return "\"" + strings.ReplaceAll(e.atom, "\"", "\"\"") + "\""
+		case sexprList: {
+			parts := make([]string, len(e.items))
+			for i, item := range e.items {
+				parts[i] = item.String()
+			}
+			return "(" + strings.Join(parts, " ") + ")"
+		}
+	}
+	return e.atom
+}
+
+func (sr *StandardReader) isSExprDelimiter(c rune) bool {
+	return unicode.IsSpace(c) || c == '(' || c == ')' || c == '|' || c == '"' || c == sr.CommentStart && c != 0
+}
+
+// nextSExpr reads one s-expression, skipping whitespace and comments before
+// each element.
+func (sr *StandardReader) nextSExpr(in *bufio.Reader) *sexpr {
+	sr.NextWS(in)
+	start := sr.cur
+	c, err := sr.readRune(in)
+	if err != nil {
+		sr.Fail(&ParseError{Start: start, End: start, Expected: []string{"s-expression"}})
+	}
+	switch(c) {
+		case '(': {
+			e := &sexpr{kind: sexprList, start: start}
+			for {
+				sr.NextWS(in)
+				if sr.TestPeek(in, ')') {
+					break
+				}
+				if _, err := in.Peek(1); err != nil {
+					sr.Fail(&ParseError{Start: sr.cur, End: sr.cur, Expected: []string{")"}})
+				}
+				e.items = append(e.items, sr.nextSExpr(in))
+			}
+			sr.readRune(in)
+			e.end = sr.cur
+			return e
+		}
+		case ')': {
+			sr.Fail(&ParseError{Start: start, End: sr.cur, Expected: []string{"s-expression"}, Found: ")"})
+		}
+		case '|', '"': {
+			var text []rune
+			for {
+				d, err := sr.readRune(in)
+				if err != nil {
+					sr.Fail(&ParseError{Start: start, End: sr.cur, Expected: []string{string(c)}})
+				}
+				if d == c {
+					if c == '"' && sr.TestPeek(in, '"') {
+						sr.readRune(in)
+					} else {
+						break
+					}
+				}
+				text = append(text, d)
+			}
+			kind := sexprQuoted
+			if c == '"' {
+				kind = sexprString
+			}
+			return &sexpr{kind: kind, atom: string(text), start: start, end: sr.cur}
+		}
+	}
+	text := []rune{c}
+	for {
+		d, err := sr.readRune(in)
+		if err != nil {
+			break
+		}
+		if sr.isSExprDelimiter(d) {
+			sr.unreadRune(in)
+			break
+		}
+		text = append(text, d)
+	}
+	return &sexpr{kind: sexprAtom, atom: string(text), start: start, end: sr.cur}
+}
+
+// SExprReader and SExprWriter read and write particles as s-expressions:
+//
+//	variable                 x
+//	function expression      (f x (c))
+//	atomic predicate         (Foo x), or P with no arguments
+//	predicate expression     (-> (Foo x) (= x y)), or (:op xor P Q)
+//	predicate comprehension  (:comp and (P y) Q)
+//	quantified predicate     (forall x (Foo x)), or (:quant some x (Foo x))
+//	quantified term          (:qterm iota y (P y))
+//
+// Operators and quantifiers with a keyword in Operators or Quantifiers are
+// written with it, and others tagged with :op and :quant.  Whether a list
+// is a term or a predicate follows from where it appears, so atoms that are
+// keywords or begin with : are tags only when unquoted; names that would be
+// mistaken for them, or that contain delimiters, are quoted |like this|.
+// Names containing | cannot be written.
+
+type SExprReader struct {
+	StandardReader
+	Operators map[string]string
+	Quantifiers map[string]string
+}
+
+type SExprWriter struct {
+	LogicWriter
+	Operators map[string]string
+	Quantifiers map[string]string
+}
+
+func defaultSExprKeywords() (map[string]string, map[string]string) {
+	return map[string]string{
+		OP_NOT: "not",
+		OP_AND: "and",
+		OP_OR: "or",
+		OP_IMPLIES: "->",
+		OP_IFF: "<->",
+	}, map[string]string{
+		QUANT_FORALL: "forall",
+		QUANT_EXISTS: "exists",
+	}
+}
+
+func GetSExprReader() *SExprReader {
+	sr := SExprReader{}
+	sr.Chain = &sr
+	sr.CommentStart = ';'
+	sr.cur = Position{Line: 1, Col: 1}
+	sr.Operators, sr.Quantifiers = defaultSExprKeywords()
+	return &sr
+}
+
+func GetSExprWriter() *SExprWriter {
+	sw := SExprWriter{}
+	sw.Operators, sw.Quantifiers = defaultSExprKeywords()
+	return &sw
+}
+
+func (sw *SExprWriter) isKeyword(name string) bool {
+	for _, kw := range sw.Operators {
+		if kw == name {
+			return true
+		}
+	}
+	for _, kw := range sw.Quantifiers {
+		if kw == name {
+			return true
+		}
+	}
+	return false
+}
+
+// symbol returns name as an atom, quoted if it could be read as anything
+// other than a name.
+func (sw *SExprWriter) symbol(name string) (string, error) {
+	plain := name != "" && name[0] != ':' && !sw.isKeyword(name)
+	for _, r := range name {
+		if unicode.IsSpace(r) || strings.ContainsRune("()|\";", r) {
+			plain = false
+		}
+	}
+	if plain {
+		return name, nil
+	}
+	if strings.ContainsRune(name, '|') {
+		return "", errors.New(fmt.Sprintf("cannot write name '%s' as an s-expression", name))
+	}
+	return "|" + name + "|", nil
+}
+
+// keyword returns the keyword for an operator or quantifier, or the tag and
+// symbol to write if it has none.
+func (sw *SExprWriter) keyword(keywords map[string]string, tag string, name string) (string, error) {
+	if kw, ok := keywords[name]; ok {
+		return kw, nil
+	}
+	sym, err := sw.symbol(name)
+	if err != nil {
+		return "", err
+	}
+	return tag + " " + sym, nil
+}
+
+func (sw *SExprWriter) Write(p Particle, out io.Writer) error {
+	var b strings.Builder
+	if err := sw.write(&b, p); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func (sw *SExprWriter) write(b *strings.Builder, p Particle) error {
+	var head string
+	var err error
+	var args []Particle
+	switch(p.Type()) {
+		case VARIABLE: head, err = sw.symbol(p.(NamedParticle).String())
+		case FUNCTION_EXPRESSION, ATOMIC_PREDICATE: {
+			tp := p.(TupleParticle)
+			head, err = sw.symbol(tp.Head().String())
+			if err == nil && (tp.Arity() > 0 || p.Type() == FUNCTION_EXPRESSION) {
+				head, args = "(" + head, tp.Arguments()
+			}
+		}
+		case PREDICATE_EXPRESSION: {
+			tp := p.(TupleParticle)
+			head, err = sw.keyword(sw.Operators, ":op", tp.Head().String())
+			head, args = "(" + head, tp.Arguments()
+		}
+		case PREDICATE_COMPREHENSION: {
+			tp := p.(TupleParticle)
+			head, err = sw.keyword(sw.Operators, "", tp.Head().String())
+			head, args = "(:comp " + strings.TrimSpace(head), tp.Arguments()
+		}
+		case QUANTIFIED_PREDICATE, QUANTIFIED_TERM: {
+			qp := p.(QuantifiedParticle)
+			if p.Type() == QUANTIFIED_TERM {
+				head, err = sw.keyword(sw.Quantifiers, "", qp.Quantifier().String())
+				head = "(:qterm " + strings.TrimSpace(head)
+			} else {
+				head, err = sw.keyword(sw.Quantifiers, ":quant", qp.Quantifier().String())
+				head = "(" + head
+			}
+			args = []Particle{qp.Variable(), qp.Argument()}
+		}
+		default: head, err = sw.symbol(p.(Name).String())
+	}
+	if err != nil {
+		return err
+	}
+	b.WriteString(head)
+	if !strings.HasPrefix(head, "(") {
+		return nil
+	}
+	for _, arg := range args {
+		b.WriteString(" ")
+		if err := sw.write(b, arg); err != nil {
+			return err
+		}
+	}
+	b.WriteString(")")
+	return nil
+}
+
+func (sr *SExprReader) fail(e *sexpr, msg string) {
+	sr.Fail(&ParseError{Start: e.start, End: e.end, Msg: msg})
+}
+
+// symbol returns the name an atom stands for, which must not be a keyword
+// or tag unless quoted.
+func (sr *SExprReader) symbol(e *sexpr) string {
+	switch(e.kind) {
+		case sexprQuoted: return e.atom
+		case sexprAtom: {
+			if e.atom[0] != ':' && sr.lookup(sr.Operators, e) == "" && sr.lookup(sr.Quantifiers, e) == "" {
+				return e.atom
+			}
+		}
+	}
+	sr.fail(e, fmt.Sprintf("expected name (found %s)", e.String()))
+	return ""
+}
+
+// lookup returns the name whose keyword an unquoted atom is, if any.
+func (sr *SExprReader) lookup(keywords map[string]string, e *sexpr) string {
+	if e.kind != sexprAtom {
+		return ""
+	}
+	for name, kw := range keywords {
+		if kw == e.atom {
+			return name
+		}
+	}
+	return ""
+}
+
+// named returns the name of an operator or quantifier, given by its keyword
+// or symbol.
+func (sr *SExprReader) named(keywords map[string]string, e *sexpr) string {
+	if name := sr.lookup(keywords, e); name != "" {
+		return name
+	}
+	return sr.symbol(e)
+}
+
+// tagged checks that a tagged list has at least n items after its tag.
+func (sr *SExprReader) tagged(e *sexpr, n int) []*sexpr {
+	if len(e.items) < n+1 {
+		sr.fail(e, fmt.Sprintf("malformed '%s'", e.head()))
+	}
+	return e.items[1:]
+}
+
+func (sr *SExprReader) quantified(e *sexpr, ptype ParticleType, q string, args []*sexpr) Particle {
+	source := sr.cSource
+	if len(args) != 2 {
+		sr.fail(e, "quantifier needs a variable and a predicate")
+	}
+	v := sr.term(args[0])
+	if v.Type() != VARIABLE {
+		sr.fail(args[0], "expected variable")
+	}
+	if ptype == QUANTIFIED_TERM {
+		return source.GetQuantifiedTerm(source.GetQuantifier(q), v.(NamedParticle), sr.predicate(args[1]))
+	}
+	return source.GetQuantifiedPredicate(source.GetQuantifier(q), v.(NamedParticle), sr.predicate(args[1]))
+}
+
+func (sr *SExprReader) term(e *sexpr) Particle {
+	source := sr.cSource
+	var p Particle
+	switch {
+		case e.kind != sexprList: p = source.GetVariableNamed(sr.symbol(e))
+		case len(e.items) == 0: sr.fail(e, "expected term")
+		case e.head() == ":comp": {
+			args := sr.tagged(e, 1)
+			op := source.GetOperator(sr.named(sr.Operators, args[0]))
+			ps := make([]Particle, len(args)-1)
+			for i, arg := range args[1:] {
+				ps[i] = sr.predicate(arg)
+			}
+			p = source.GetPredicateComprehension(op, ps...)
+		}
+		case e.head() == ":qterm": {
+			args := sr.tagged(e, 1)
+			p = sr.quantified(e, QUANTIFIED_TERM, sr.named(sr.Quantifiers, args[0]), args[1:])
+		}
+		default: {
+			fn := source.GetFunctionName(sr.symbol(e.items[0]))
+			ps := make([]Particle, len(e.items)-1)
+			for i, arg := range e.items[1:] {
+				ps[i] = sr.term(arg)
+			}
+			p = source.GetFunctionExpression(fn, ps...)
+		}
+	}
+	sr.Record(p, e.start, e.end)
+	return p
+}
+
+func (sr *SExprReader) predicate(e *sexpr) Particle {
+	source := sr.cSource
+	var p Particle
+	switch {
+		case e.kind != sexprList: p = source.GetAtomicPredicate(source.GetPredicateName(sr.symbol(e)))
+		case len(e.items) == 0: sr.fail(e, "expected predicate")
+		case e.head() == ":op" || sr.lookup(sr.Operators, e.items[0]) != "": {
+			args := e.items[1:]
+			var op string
+			if e.head() == ":op" {
+				args = sr.tagged(e, 1)
+				op, args = sr.named(sr.Operators, args[0]), args[1:]
+			} else {
+				op = sr.lookup(sr.Operators, e.items[0])
+			}
+			ps := make([]Particle, len(args))
+			for i, arg := range args {
+				ps[i] = sr.predicate(arg)
+			}
+			p = source.GetPredicateExpression(source.GetOperator(op), ps...)
+		}
+		case e.head() == ":quant": {
+			args := sr.tagged(e, 1)
+			p = sr.quantified(e, QUANTIFIED_PREDICATE, sr.named(sr.Quantifiers, args[0]), args[1:])
+		}
+		case sr.lookup(sr.Quantifiers, e.items[0]) != "": {
+			p = sr.quantified(e, QUANTIFIED_PREDICATE, sr.lookup(sr.Quantifiers, e.items[0]), e.items[1:])
+		}
+		default: {
+			pred := source.GetPredicateName(sr.symbol(e.items[0]))
+			ps := make([]Particle, len(e.items)-1)
+			for i, arg := range e.items[1:] {
+				ps[i] = sr.term(arg)
+			}
+			p = source.GetAtomicPredicate(pred, ps...)
+		}
+	}
+	sr.Record(p, e.start, e.end)
+	return p
+}
+
+func (sr *SExprReader) read(source ParticleSource, in *bufio.Reader, ptype ParticleType) (rp Particle, re error) {
+	defer sr.recoverError(&rp, &re)
+	sr.bind(source, in)
+	e := sr.nextSExpr(in)
+	switch(ptype) {
+		case VARIABLE, FUNCTION_EXPRESSION, PREDICATE_COMPREHENSION, QUANTIFIED_TERM: rp = sr.term(e)
+		case ATOMIC_PREDICATE, PREDICATE_EXPRESSION, QUANTIFIED_PREDICATE: rp = sr.predicate(e)
+		default: {
+			rp = source.GetName(ptype, sr.symbol(e))
+			sr.Record(rp, e.start, e.end)
+		}
+	}
+	if rp.Type() != ptype {
+		sr.fail(e, fmt.Sprintf("expected %s (found %s)", ptype.String(), rp.Type().String()))
+	}
+	return rp, nil
+}
+
+// ReadTerm and ReadPredicate read a term or predicate of any type.
+func (sr *SExprReader) ReadTerm(source ParticleSource, in *bufio.Reader) (rp Particle, re error) {
+	defer sr.recoverError(&rp, &re)
+	sr.bind(source, in)
+	return sr.term(sr.nextSExpr(in)), nil
+}
+
+func (sr *SExprReader) ReadPredicate(source ParticleSource, in *bufio.Reader) (rp Particle, re error) {
+	defer sr.recoverError(&rp, &re)
+	sr.bind(source, in)
+	return sr.predicate(sr.nextSExpr(in)), nil
+}
+
+func (sr *SExprReader) ReadParticle(source ParticleSource, ptype ParticleType, in *bufio.Reader) (Particle, error) {
+	if ptype == NAME {
+		return nil, errors.New("cannot read untyped name")
+	}
+	return sr.read(source, in, ptype)
+}
@@ -0,0 +1,96 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSExpr(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x := source.GetVariableNamed("x")
+	y := source.GetVariableNamed("y")
+	atom := func(name string, args ...Particle) Particle {
+		return source.GetAtomicPredicate(source.GetPredicateName(name), args...)
+	}
+	fn := func(name string, args ...Particle) Particle {
+		return source.GetFunctionExpression(source.GetFunctionName(name), args...)
+	}
+	P, Q := atom("P"), atom("Q")
+	cases := []struct {
+		p Particle
+		text string
+	}{
+		{source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), x,
+			source.GetPredicateExpression(source.GetOperator(OP_IMPLIES), atom("Foo", x), atom(PRED_EQUALS, x, y))),
+			"(forall x (-> (Foo x) (= x y)))"},
+		{x, "x"},
+		{fn("f", x, fn("c")), "(f x (c))"},
+		{source.GetPredicateExpression(source.GetOperator(OP_NOT), source.GetPredicateExpression(source.GetOperator(OP_OR), P, Q)),
+			"(not (or P Q))"},
+		{source.GetPredicateExpression(source.GetOperator("xor"), P, atom("Member", x,
+			source.GetPredicateComprehension(source.GetOperator(OP_AND), atom("P", y), Q))),
+			"(:op xor P (Member x (:comp and (P y) Q)))"},
+		{atom(PRED_EQUALS, fn("f", x), source.GetQuantifiedTerm(source.GetQuantifier("iota"), y, atom("P", y))),
+			"(= (f x) (:qterm iota y (P y)))"},
+		{source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_EXISTS), y,
+			source.GetQuantifiedPredicate(source.GetQuantifier("most"), x, atom("and", x, y))),
+			"(exists y (:quant most x (|and| x y)))"},
+		{source.GetPredicateComprehension(source.GetOperator("or else"), atom(":p"), atom("a(b)")),
+			"(:comp |or else| |:p| |a(b)|)"},
+	}
+	sw := GetSExprWriter()
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := sw.Write(c.p, &buf); err != nil {
+			t.Errorf("%s: %s", c.text, err.Error())
+			continue
+		}
+		if buf.String() != c.text {
+			t.Errorf("wrote '%s', expected '%s'", buf.String(), c.text)
+		}
+		p, err := GetSExprReader().ReadParticle(source, c.p.Type(), bufio.NewReader(strings.NewReader(c.text)))
+		if err != nil {
+			t.Errorf("%s: %s", c.text, err.Error())
+		} else if !p.Equals(c.p) {
+			t.Errorf("'%s' did not round trip", c.text)
+		}
+	}
+	if err := sw.Write(atom("a|b"), &bytes.Buffer{}); err == nil {
+		t.Errorf("wrote a name containing |")
+	}
+}
+
+func TestSExprRead(t *testing.T) {
+	source := CreateBasicParticleSource()
+	p, err := GetSExprReader().ReadPredicate(source, bufio.NewReader(strings.NewReader("; comment\n(and (P) (:op -> Q R))")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	GetSExprWriter().Write(p, &buf)
+	if buf.String() != "(and P (-> Q R))" {
+		t.Errorf("read '%s'", buf.String())
+	}
+	errors := []struct {
+		text string
+		ptype ParticleType
+		msg string
+	}{
+		{"(forall (P x))", QUANTIFIED_PREDICATE, "quantifier needs a variable and a predicate"},
+		{"(forall (f) P)", QUANTIFIED_PREDICATE, "expected variable"},
+		{"(and P", PREDICATE_EXPRESSION, "expected ')'"},
+		{"(P forall)", ATOMIC_PREDICATE, "expected name (found forall)"},
+		{"(:comp)", PREDICATE_COMPREHENSION, "malformed ':comp'"},
+		{"(f x)", VARIABLE, "expected variable (found function-expression)"},
+	}
+	for _, c := range errors {
+		_, err := GetSExprReader().ReadParticle(source, c.ptype, bufio.NewReader(strings.NewReader(c.text)))
+		if err == nil {
+			t.Errorf("read '%s' without error", c.text)
+		} else if !strings.Contains(err.Error(), c.msg) {
+			t.Errorf("reading '%s': %s", c.text, err.Error())
+		}
+	}
+}
@@ -0,0 +1,700 @@
+package logic
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SMT-LIB 2 scripts are written for the logic UF over a single sort of
+// individuals, with every function and predicate used in the formulas
+// declared before they are asserted:
+//
+//	(set-logic UF)
+//	(declare-sort U 0)
+//	(declare-fun f (U) U)
+//	(declare-fun P (U U) Bool)
+//	(assert (forall ((x U)) (=> (P x (f x)) (= x y))))
+//	(check-sat)
+//
+// The operators ~ & | -> <-> are written as not, and, or, => and = between
+// formulas, and the quantifiers A and E as forall and exists.  Free
+// variables are declared as constants, and so read back as functions with no
+// arguments.
+//
+// The reader takes the uninterpreted-function core of the format.  Symbols
+// bound by forall and exists are read as variables, whatever their sort,
+// except that Bool variables are not supported; other symbols are functions
+// or predicates according to their declarations, or their position if
+// undeclared.  = between formulas is <->, and distinct and chained = are
+// read as conjunctions of pairs.  Annotations (! e ...) are ignored.
+
+type SMTLIBWriter struct {
+	LogicWriter
+	Logic string
+	Sort string
+}
+
+type SMTLIBReader struct {
+	StandardReader
+	functions map[string]smtFunction
+	bound []string
+}
+
+// smtFunction is the declaration of a function or predicate symbol.
+type smtFunction struct {
+	arity int
+	predicate bool
+}
+
+var smtOperators = map[string]string{
+	OP_NOT: "not",
+	OP_AND: "and",
+	OP_OR: "or",
+	OP_IMPLIES: "=>",
+	OP_IFF: "=",
+}
+
+var smtQuantifiers = map[string]string{
+	QUANT_FORALL: "forall",
+	QUANT_EXISTS: "exists",
+}
+
+// Symbols that cannot be declared, because they are reserved words or
+// belong to the core theory.
+var smtReserved = map[string]bool{
+	"!": true, "_": true, "as": true, "let": true, "exists": true, "forall": true, "match": true, "par": true,
+	"true": true, "false": true, "not": true, "and": true, "or": true, "xor": true, "=>": true, "=": true,
+	"distinct": true, "ite": true, "Bool": true,
+}
+
+func GetSMTLIBWriter() *SMTLIBWriter {
+	return &SMTLIBWriter{Logic: "UF", Sort: "U"}
+}
+
+func GetSMTLIBReader() *SMTLIBReader {
+	sr := SMTLIBReader{}
+	sr.Chain = &sr
+	sr.CommentStart = ';'
+	sr.cur = Position{Line: 1, Col: 1}
+	return &sr
+}
+
+func isSMTSymbolChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("~!@$%^&*_-+=<>.?/", r)
+}
+
+// smtSymbol returns name as an SMT-LIB symbol, quoted with | if it is not a
+// simple symbol.
+func smtSymbol(name string) (string, error) {
+	simple := name != "" && !(name[0] >= '0' && name[0] <= '9') && !smtReserved[name]
+	for _, r := range name {
+		if !isSMTSymbolChar(r) {
+			simple = false
+		}
+	}
+	if simple {
+		return name, nil
+	}
+	if strings.ContainsAny(name, "|\\") {
+		return "", errors.New(fmt.Sprintf("cannot write name '%s' in SMT-LIB", name))
+	}
+	return "|" + name + "|", nil
+}
+
+func (sw *SMTLIBWriter) Write(p Particle, out io.Writer) error {
+	var b strings.Builder
+	var err error
+	switch(p.Type()) {
+		case VARIABLE, FUNCTION_EXPRESSION: err = sw.writeTerm(&b, p)
+		default: err = sw.writeFormula(&b, p)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, b.String())
+	return err
+}
+
+// WriteScript writes a script declaring the sort and the signature of
+// formulas, asserting each of them, and checking satisfiability.
+func (sw *SMTLIBWriter) WriteScript(formulas []Particle, out io.Writer) error {
+	functions := make(map[string]smtFunction)
+	for _, f := range formulas {
+		if err := sw.signature(f, nil, functions); err != nil {
+			return err
+		}
+	}
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sortName, err := smtSymbol(sw.Sort)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	if sw.Logic != "" {
+		fmt.Fprintf(&b, "(set-logic %s)\n", sw.Logic)
+	}
+	fmt.Fprintf(&b, "(declare-sort %s 0)\n", sortName)
+	for _, name := range names {
+		fn := functions[name]
+		sym, err := smtSymbol(name)
+		if err != nil {
+			return err
+		}
+		result := sortName
+		if fn.predicate {
+			result = "Bool"
+		}
+		fmt.Fprintf(&b, "(declare-fun %s (%s) %s)\n", sym, strings.TrimSpace(strings.Repeat(sortName+" ", fn.arity)), result)
+	}
+	for _, f := range formulas {
+		b.WriteString("(assert ")
+		if err := sw.writeFormula(&b, f); err != nil {
+			return err
+		}
+		b.WriteString(")\n")
+	}
+	b.WriteString("(check-sat)\n")
+	_, err = io.WriteString(out, b.String())
+	return err
+}
+
+// signature adds the functions, predicates and free variables of p to
+// functions, failing if a symbol is used inconsistently.
+func (sw *SMTLIBWriter) signature(p Particle, bound []string, functions map[string]smtFunction) error {
+	var name string
+	var fn smtFunction
+	switch(p.Type()) {
+		case VARIABLE: {
+			name = p.(NamedParticle).String()
+			for _, v := range bound {
+				if v == name {
+					return nil
+				}
+			}
+		}
+		case FUNCTION_EXPRESSION: {
+			tp := p.(TupleParticle)
+			name, fn = tp.Head().String(), smtFunction{arity: tp.Arity()}
+		}
+		case ATOMIC_PREDICATE: {
+			tp := p.(TupleParticle)
+			if tp.Head().String() != PRED_EQUALS || tp.Arity() != 2 {
+				name, fn = tp.Head().String(), smtFunction{arity: tp.Arity(), predicate: true}
+			}
+		}
+		case QUANTIFIED_PREDICATE: {
+			qp := p.(QuantifiedParticle)
+			return sw.signature(qp.Argument(), append(bound, qp.Variable().String()), functions)
+		}
+	}
+	if name != "" || p.Type() == VARIABLE {
+		if prev, ok := functions[name]; ok && prev != fn {
+			return errors.New(fmt.Sprintf("symbol '%s' is used with different arities or sorts", name))
+		}
+		functions[name] = fn
+	}
+	for _, part := range p.Parts() {
+		if !part.Name() {
+			if err := sw.signature(part, bound, functions); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (sw *SMTLIBWriter) writeSymbol(b *strings.Builder, name string) error {
+	sym, err := smtSymbol(name)
+	if err != nil {
+		return err
+	}
+	b.WriteString(sym)
+	return nil
+}
+
+func (sw *SMTLIBWriter) writeTerm(b *strings.Builder, p Particle) error {
+	switch(p.Type()) {
+		case VARIABLE: return sw.writeSymbol(b, p.(NamedParticle).String())
+		case FUNCTION_EXPRESSION: {
+			tp := p.(TupleParticle)
+			return sw.writeApplication(b, tp.Head().String(), tp.Arguments(), sw.writeTerm)
+		}
+	}
+	return errors.New(fmt.Sprintf("cannot write %s as an SMT-LIB term", p.Type().String()))
+}
+
+func (sw *SMTLIBWriter) writeApplication(b *strings.Builder, head string, args []Particle, write func(*strings.Builder, Particle) error) error {
+	if len(args) == 0 {
+		return sw.writeSymbol(b, head)
+	}
+	b.WriteString("(")
+	if err := sw.writeSymbol(b, head); err != nil {
+		return err
+	}
+	for _, arg := range args {
+		b.WriteString(" ")
+		if err := write(b, arg); err != nil {
+			return err
+		}
+	}
+	b.WriteString(")")
+	return nil
+}
+
+func (sw *SMTLIBWriter) writeFormula(b *strings.Builder, p Particle) error {
+	switch(p.Type()) {
+		case ATOMIC_PREDICATE: {
+			tp := p.(TupleParticle)
+			if tp.Head().String() == PRED_EQUALS && tp.Arity() == 2 {
+				b.WriteString("(= ")
+				if err := sw.writeTerm(b, tp.Argument(0)); err != nil {
+					return err
+				}
+				b.WriteString(" ")
+				if err := sw.writeTerm(b, tp.Argument(1)); err != nil {
+					return err
+				}
+				b.WriteString(")")
+				return nil
+			}
+			return sw.writeApplication(b, tp.Head().String(), tp.Arguments(), sw.writeTerm)
+		}
+		case PREDICATE_EXPRESSION: {
+			tp := p.(TupleParticle)
+			op, ok := smtOperators[tp.Head().String()]
+			arity := tp.Arity()
+			switch {
+				case !ok: return errors.New(fmt.Sprintf("operator '%s' has no SMT-LIB equivalent", tp.Head().String()))
+				case op == "not" && arity != 1, op != "not" && arity < 2, op == "=" && arity != 2: {
+					return errors.New(fmt.Sprintf("cannot write '%s' with %d arguments in SMT-LIB", tp.Head().String(), arity))
+				}
+			}
+			b.WriteString("(")
+			b.WriteString(op)
+			for _, arg := range tp.Arguments() {
+				b.WriteString(" ")
+				if err := sw.writeFormula(b, arg); err != nil {
+					return err
+				}
+			}
+			b.WriteString(")")
+			return nil
+		}
+		case QUANTIFIED_PREDICATE: {
+			qp := p.(QuantifiedParticle)
+			q := qp.Quantifier().String()
+			keyword, ok := smtQuantifiers[q]
+			if !ok {
+				return errors.New(fmt.Sprintf("quantifier '%s' has no SMT-LIB equivalent", q))
+			}
+			sortName, err := smtSymbol(sw.Sort)
+			if err != nil {
+				return err
+			}
+			b.WriteString("(")
+			b.WriteString(keyword)
+			b.WriteString(" (")
+			for i := 0; ; i++ {
+				if i > 0 {
+					b.WriteString(" ")
+				}
+				b.WriteString("(")
+				if err := sw.writeSymbol(b, qp.Variable().String()); err != nil {
+					return err
+				}
+				b.WriteString(" ")
+				b.WriteString(sortName)
+				b.WriteString(")")
+				next, ok := qp.Argument().(QuantifiedParticle)
+				if !ok || next.Type() != QUANTIFIED_PREDICATE || next.Quantifier().String() != q {
+					break
+				}
+				qp = next
+			}
+			b.WriteString(") ")
+			if err := sw.writeFormula(b, qp.Argument()); err != nil {
+				return err
+			}
+			b.WriteString(")")
+			return nil
+		}
+	}
+	return errors.New(fmt.Sprintf("cannot write %s as an SMT-LIB formula", p.Type().String()))
+}
+
+func (sr *SMTLIBReader) fail(e *sexpr, msg string) {
+	sr.Fail(&ParseError{Start: e.start, End: e.end, Msg: msg})
+}
+
+func (sr *SMTLIBReader) isBound(name string) bool {
+	for i := len(sr.bound)-1; i >= 0; i-- {
+		if sr.bound[i] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// symbol returns the name of an atom that is a symbol.
+func (sr *SMTLIBReader) symbol(e *sexpr) string {
+	switch {
+		case e.kind == sexprQuoted: return e.atom
+		case e.kind == sexprAtom && (e.atom == "true" || e.atom == "false"): {
+			sr.fail(e, fmt.Sprintf("unsupported constant %s", e.atom))
+		}
+		case e.kind != sexprAtom || smtReserved[e.atom]: {
+			sr.fail(e, fmt.Sprintf("expected symbol (found %s)", e.String()))
+		}
+		case e.atom[0] >= '0' && e.atom[0] <= '9' || e.atom[0] == '#' || e.atom[0] == ':': {
+			sr.fail(e, fmt.Sprintf("unsupported constant %s", e.atom))
+		}
+	}
+	return e.atom
+}
+
+// annotated returns the expression inside any annotations.
+func (sr *SMTLIBReader) annotated(e *sexpr) *sexpr {
+	for e.head() == "!" {
+		if len(e.items) < 2 {
+			sr.fail(e, "empty annotation")
+		}
+		e = e.items[1]
+	}
+	return e
+}
+
+// isFormula reports whether e has sort Bool.
+func (sr *SMTLIBReader) isFormula(e *sexpr) bool {
+	e = sr.annotated(e)
+	var name string
+	switch(e.kind) {
+		case sexprList: {
+			if len(e.items) == 0 {
+				return false
+			}
+			switch(e.head()) {
+				case "not", "and", "or", "=>", "=", "distinct", "forall", "exists", "xor": return true
+			}
+			if e.items[0].kind != sexprAtom && e.items[0].kind != sexprQuoted {
+				return false
+			}
+			name = e.items[0].atom
+		}
+		case sexprAtom, sexprQuoted: {
+			if e.kind == sexprAtom && (e.atom == "true" || e.atom == "false") {
+				return true
+			}
+			if sr.isBound(e.atom) {
+				return false
+			}
+			name = e.atom
+		}
+	}
+	return sr.functions[name].predicate
+}
+
+func (sr *SMTLIBReader) term(e *sexpr) Particle {
+	source := sr.cSource
+	e = sr.annotated(e)
+	var p Particle
+	if e.kind == sexprList {
+		if len(e.items) < 2 {
+			sr.fail(e, "expected term")
+		}
+		name := sr.symbol(e.items[0])
+		if fn, ok := sr.functions[name]; ok && fn.predicate {
+			sr.fail(e, fmt.Sprintf("expected term (found predicate '%s')", name))
+		}
+		args := make([]Particle, len(e.items)-1)
+		for i, arg := range e.items[1:] {
+			args[i] = sr.term(arg)
+		}
+		p = source.GetFunctionExpression(source.GetFunctionName(name), args...)
+	} else {
+		name := sr.symbol(e)
+		if sr.isBound(name) {
+			p = source.GetVariableNamed(name)
+		} else {
+			if fn, ok := sr.functions[name]; ok && fn.predicate {
+				sr.fail(e, fmt.Sprintf("expected term (found predicate '%s')", name))
+			}
+			p = source.GetFunctionExpression(source.GetFunctionName(name))
+		}
+	}
+	sr.Record(p, e.start, e.end)
+	return p
+}
+
+// pairs returns the conjunction of rel applied to each pair of args, which
+// are taken pairwise if all is set or consecutively if not.
+func (sr *SMTLIBReader) pairs(e *sexpr, all bool, rel func(a *sexpr, b *sexpr) Particle) Particle {
+	args := e.items[1:]
+	if len(args) < 2 {
+		sr.fail(e, fmt.Sprintf("'%s' needs at least two arguments", e.head()))
+	}
+	var ps []Particle
+	for i := range args {
+		for j := i+1; j < len(args); j++ {
+			if !all && j > i+1 {
+				break
+			}
+			ps = append(ps, rel(args[i], args[j]))
+		}
+	}
+	if len(ps) == 1 {
+		return ps[0]
+	}
+	return sr.cSource.GetPredicateExpression(sr.cSource.GetOperator(OP_AND), ps...)
+}
+
+func (sr *SMTLIBReader) formula(e *sexpr) Particle {
+	source := sr.cSource
+	e = sr.annotated(e)
+	var p Particle
+	if e.kind != sexprList {
+		name := sr.symbol(e)
+		if sr.isBound(name) {
+			sr.fail(e, fmt.Sprintf("unsupported Bool variable '%s'", name))
+		}
+		if fn, ok := sr.functions[name]; ok && !fn.predicate {
+			sr.fail(e, fmt.Sprintf("expected formula (found function '%s')", name))
+		}
+		p = source.GetAtomicPredicate(source.GetPredicateName(name))
+		sr.Record(p, e.start, e.end)
+		return p
+	}
+	if len(e.items) == 0 {
+		sr.fail(e, "expected formula")
+	}
+	args := e.items[1:]
+	switch(e.head()) {
+		case "not", "and", "or", "=>": {
+			if e.head() == "not" && len(args) != 1 || e.head() != "not" && len(args) < 2 {
+				sr.fail(e, fmt.Sprintf("wrong number of arguments to '%s'", e.head()))
+			}
+			ps := make([]Particle, len(args))
+			for i, arg := range args {
+				ps[i] = sr.formula(arg)
+			}
+			switch(e.head()) {
+				case "not": p = source.GetPredicateExpression(source.GetOperator(OP_NOT), ps...)
+				case "and": p = source.GetPredicateExpression(source.GetOperator(OP_AND), ps...)
+				case "or": p = source.GetPredicateExpression(source.GetOperator(OP_OR), ps...)
+				case "=>": {
+					p = ps[len(ps)-1]
+					for i := len(ps)-2; i >= 0; i-- {
+						p = source.GetPredicateExpression(source.GetOperator(OP_IMPLIES), ps[i], p)
+					}
+				}
+			}
+		}
+		case "=": {
+			p = sr.pairs(e, false, func(a *sexpr, b *sexpr) Particle {
+				if sr.isFormula(a) || sr.isFormula(b) {
+					return source.GetPredicateExpression(source.GetOperator(OP_IFF), sr.formula(a), sr.formula(b))
+				}
+				return source.GetAtomicPredicate(source.GetPredicateName(PRED_EQUALS), sr.term(a), sr.term(b))
+			})
+		}
+		case "distinct": {
+			p = sr.pairs(e, true, func(a *sexpr, b *sexpr) Particle {
+				var eq Particle
+				if sr.isFormula(a) || sr.isFormula(b) {
+					eq = source.GetPredicateExpression(source.GetOperator(OP_IFF), sr.formula(a), sr.formula(b))
+				} else {
+					eq = source.GetAtomicPredicate(source.GetPredicateName(PRED_EQUALS), sr.term(a), sr.term(b))
+				}
+				return source.GetPredicateExpression(source.GetOperator(OP_NOT), eq)
+			})
+		}
+		case "forall", "exists": {
+			if len(args) != 2 || args[0].kind != sexprList || len(args[0].items) == 0 {
+				sr.fail(e, fmt.Sprintf("malformed '%s'", e.head()))
+			}
+			q := QUANT_FORALL
+			if e.head() == "exists" {
+				q = QUANT_EXISTS
+			}
+			var vars []NamedParticle
+			for _, binding := range args[0].items {
+				if binding.kind != sexprList || len(binding.items) != 2 {
+					sr.fail(binding, "malformed variable binding")
+				}
+				if binding.items[1].isAtom("Bool") {
+					sr.fail(binding, "unsupported Bool variable")
+				}
+				name := sr.symbol(binding.items[0])
+				v := source.GetVariableNamed(name)
+				sr.Record(v, binding.items[0].start, binding.items[0].end)
+				vars = append(vars, v)
+				sr.bound = append(sr.bound, name)
+			}
+			p = sr.formula(args[1])
+			sr.bound = sr.bound[:len(sr.bound)-len(vars)]
+			for i := len(vars)-1; i >= 0; i-- {
+				p = source.GetQuantifiedPredicate(source.GetQuantifier(q), vars[i], p)
+			}
+		}
+		default: {
+			name := sr.symbol(e.items[0])
+			if fn, ok := sr.functions[name]; ok && !fn.predicate {
+				sr.fail(e, fmt.Sprintf("expected formula (found function '%s')", name))
+			}
+			ps := make([]Particle, len(args))
+			for i, arg := range args {
+				ps[i] = sr.term(arg)
+			}
+			p = source.GetAtomicPredicate(source.GetPredicateName(name), ps...)
+		}
+	}
+	sr.Record(p, e.start, e.end)
+	return p
+}
+
+func (sr *SMTLIBReader) read(source ParticleSource, in *bufio.Reader, formula bool) (rp Particle, re error) {
+	defer sr.recoverError(&rp, &re)
+	sr.bind(source, in)
+	sr.bound = nil
+	e := sr.nextSExpr(in)
+	if formula {
+		return sr.formula(e), nil
+	}
+	return sr.term(e), nil
+}
+
+// ReadTerm and ReadPredicate read a single expression, taking symbols
+// declared by an earlier ReadScript into account.
+func (sr *SMTLIBReader) ReadTerm(source ParticleSource, in *bufio.Reader) (Particle, error) {
+	return sr.read(source, in, false)
+}
+
+func (sr *SMTLIBReader) ReadPredicate(source ParticleSource, in *bufio.Reader) (Particle, error) {
+	return sr.read(source, in, true)
+}
+
+func (sr *SMTLIBReader) ReadParticle(source ParticleSource, ptype ParticleType, in *bufio.Reader) (rp Particle, re error) {
+	switch(ptype) {
+		case VARIABLE: return nil, errors.New("SMT-LIB variables are read only within quantifiers")
+		case FUNCTION_EXPRESSION: rp, re = sr.ReadTerm(source, in)
+		case ATOMIC_PREDICATE, PREDICATE_EXPRESSION, QUANTIFIED_PREDICATE: rp, re = sr.ReadPredicate(source, in)
+		case PREDICATE_COMPREHENSION, QUANTIFIED_TERM: {
+			return nil, errors.New(fmt.Sprintf("SMT-LIB has no syntax for %s", ptype.String()))
+		}
+		default: {
+			return sr.StandardReader.ReadParticle(source, ptype, in)
+		}
+	}
+	if re == nil && rp.Type() != ptype {
+		return nil, errors.New(fmt.Sprintf("expected %s (found %s)", ptype.String(), rp.Type().String()))
+	}
+	return rp, re
+}
+
+// ReadScript reads the commands of a script, returning the asserted
+// formulas.  Declarations are checked and remembered; commands that only
+// direct the solver are skipped.  A malformed command is reported and the
+// rest of the script read, but reading stops at unbalanced parentheses.
+func (sr *SMTLIBReader) ReadScript(source ParticleSource, in *bufio.Reader) ([]Particle, []*ParseError) {
+	var asserted []Particle
+	var errs []*ParseError
+	sr.Spans = NewSpanTable()
+	sr.functions = make(map[string]smtFunction)
+	sr.bind(source, in)
+	sorts := make(map[string]bool)
+	for {
+		var e *sexpr
+		if pe := sr.catch(func() {
+			sr.NextWS(in)
+			if _, err := in.Peek(1); err == nil {
+				e = sr.nextSExpr(in)
+			}
+		}); pe != nil {
+			errs = append(errs, pe)
+			break
+		}
+		if e == nil {
+			break
+		}
+		if pe := sr.catch(func() {
+			if p := sr.command(e, sorts); p != nil {
+				asserted = append(asserted, p)
+			}
+		}); pe != nil {
+			errs = append(errs, pe)
+		}
+	}
+	return asserted, errs
+}
+
+func (sr *SMTLIBReader) catch(fn func()) (pe *ParseError) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe = sr.asParseError(r)
+		}
+	}()
+	fn()
+	return nil
+}
+
+func (sr *SMTLIBReader) command(e *sexpr, sorts map[string]bool) Particle {
+	if e.kind != sexprList || e.head() == "" {
+		sr.fail(e, "expected command")
+	}
+	args := e.items[1:]
+	switch(e.head()) {
+		case "assert": {
+			if len(args) != 1 {
+				sr.fail(e, "malformed assert")
+			}
+			sr.bound = nil
+			return sr.formula(args[0])
+		}
+		case "declare-sort": {
+			if len(args) != 2 || !args[1].isAtom("0") {
+				sr.fail(e, "only sorts of arity 0 are supported")
+			}
+			sorts[sr.symbol(args[0])] = true
+		}
+		case "declare-fun", "declare-const": {
+			var params []*sexpr
+			var result *sexpr
+			switch {
+				case e.head() == "declare-const" && len(args) == 2: result = args[1]
+				case e.head() == "declare-fun" && len(args) == 3 && args[1].kind == sexprList: {
+					params, result = args[1].items, args[2]
+				}
+				default: sr.fail(e, fmt.Sprintf("malformed %s", e.head()))
+			}
+			for _, s := range append(params, result) {
+				if !s.isAtom("Bool") && (s.kind == sexprList || !sorts[s.atom]) {
+					sr.fail(s, fmt.Sprintf("undeclared sort %s", s.String()))
+				}
+			}
+			for _, s := range params {
+				if s.isAtom("Bool") {
+					sr.fail(s, "unsupported Bool argument")
+				}
+			}
+			name := sr.symbol(args[0])
+			if _, ok := sr.functions[name]; ok {
+				sr.fail(args[0], fmt.Sprintf("'%s' is already declared", name))
+			}
+			sr.functions[name] = smtFunction{arity: len(params), predicate: result.isAtom("Bool")}
+		}
+		case "set-logic", "set-info", "set-option", "check-sat", "get-model", "get-info", "get-option",
+			"get-proof", "get-unsat-core", "push", "pop", "exit", "echo": {
+		}
+		default: {
+			sr.fail(e, fmt.Sprintf("unsupported command '%s'", e.head()))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSMTLIBScript(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x := source.GetVariableNamed("x")
+	y := source.GetVariableNamed("y")
+	c := source.GetFunctionExpression(source.GetFunctionName("c"))
+	fx := source.GetFunctionExpression(source.GetFunctionName("f"), x)
+	formulas := []Particle{
+		source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), x,
+			source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), y,
+				source.GetPredicateExpression(source.GetOperator(OP_IMPLIES),
+					source.GetAtomicPredicate(source.GetPredicateName("P"), x, fx),
+					source.GetAtomicPredicate(source.GetPredicateName(PRED_EQUALS), x, y)))),
+		source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_EXISTS), x,
+			source.GetPredicateExpression(source.GetOperator(OP_IFF),
+				source.GetAtomicPredicate(source.GetPredicateName("Is zero"), x),
+				source.GetPredicateExpression(source.GetOperator(OP_NOT),
+					source.GetPredicateExpression(source.GetOperator(OP_OR),
+						source.GetAtomicPredicate(source.GetPredicateName("Q")),
+						source.GetAtomicPredicate(source.GetPredicateName("P"), c, c),
+						source.GetAtomicPredicate(source.GetPredicateName("and"), x))))),
+	}
+	var buf bytes.Buffer
+	if err := GetSMTLIBWriter().WriteScript(formulas, &buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.Join([]string{
+		"(set-logic UF)",
+		"(declare-sort U 0)",
+		"(declare-fun |Is zero| (U) Bool)",
+		"(declare-fun P (U U) Bool)",
+		"(declare-fun Q () Bool)",
+		"(declare-fun |and| (U) Bool)",
+		"(declare-fun c () U)",
+		"(declare-fun f (U) U)",
+		"(assert (forall ((x U) (y U)) (=> (P x (f x)) (= x y))))",
+		"(assert (exists ((x U)) (= (|Is zero| x) (not (or Q (P c c) (|and| x))))))",
+		"(check-sat)",
+	}, "\n") + "\n"
+	if buf.String() != expected {
+		t.Errorf("wrote\n%s", buf.String())
+	}
+	asserted, errs := GetSMTLIBReader().ReadScript(source, bufio.NewReader(strings.NewReader(buf.String())))
+	if len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+	if len(asserted) != len(formulas) {
+		t.Fatalf("read %d formulas", len(asserted))
+	}
+	for i, p := range asserted {
+		if !p.Equals(formulas[i]) {
+			t.Errorf("formula %d did not round trip", i)
+		}
+	}
+}
+
+func TestSMTLIBReader(t *testing.T) {
+	source := CreateBasicParticleSource()
+	script := `; a hand-written benchmark
+(set-info :source |Written by hand;
+spanning lines|)
+(set-info :status "sat ""probably""")
+(set-logic UF)
+(declare-sort S 0)
+(declare-const a S)
+(declare-fun g (S S) S)
+(declare-fun p () Bool)
+(declare-fun R (S) Bool)
+(assert (! (=> p (R a) (distinct a (g a a) b)) :named first))
+(assert (forall ((v S)) (= (R v) p (R (g v a)))))
+(assert (= a b (g a b)))
+(check-sat)
+(exit)
+`
+	asserted, errs := GetSMTLIBReader().ReadScript(source, bufio.NewReader(strings.NewReader(script)))
+	if len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+	var ws []string
+	for _, p := range asserted {
+		var buf bytes.Buffer
+		GetStandardWriter().Write(p, &buf)
+		ws = append(ws, buf.String())
+	}
+	expected := []string{
+		"{->:p[],{->:R[a()],{&:{~:=[a(),g(a(),a())]},{~:=[a(),b()]},{~:=[g(a(),a()),b()]}}}}",
+		"A$v:{&:{<->:R[$v],p[]},{<->:p[],R[g($v,a())]}}:",
+		"{&:=[a(),b()],=[b(),g(a(),b())]}",
+	}
+	if strings.Join(ws, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("read\n%s", strings.Join(ws, "\n"))
+	}
+	bad := `(declare-sort S 0)
+(declare-fun f (S) S)
+(assert (f a))
+(declare-fun f (S) Bool)
+(assert (forall ((b Bool)) b))
+(assert true)
+(define-fun h () S a)
+(assert (R a)
+`
+	asserted, errs = GetSMTLIBReader().ReadScript(source, bufio.NewReader(strings.NewReader(bad)))
+	if len(asserted) != 0 || len(errs) != 6 {
+		t.Errorf("read %d formulas with %d errors", len(asserted), len(errs))
+	}
+	for i, line := range []int{3, 4, 5, 6, 7} {
+		if i < len(errs) && errs[i].Start.Line != line {
+			t.Errorf("error %d at %s: %s", i, errs[i].Start.String(), errs[i].Error())
+		}
+	}
+	for _, p := range []Particle{
+		source.GetQuantifiedPredicate(source.GetQuantifier("I"), source.GetVariableNamed("x"), source.GetAtomicPredicate(source.GetPredicateName("P"))),
+		source.GetPredicateExpression(source.GetOperator(OP_AND), source.GetAtomicPredicate(source.GetPredicateName("P"))),
+		source.GetAtomicPredicate(source.GetPredicateName("bar|"), source.GetVariableNamed("x")),
+	} {
+		if err := GetSMTLIBWriter().Write(p, &bytes.Buffer{}); err == nil {
+			t.Errorf("expected error writing %s", p.Type().String())
+		}
+	}
+	mixed := []Particle{
+		source.GetAtomicPredicate(source.GetPredicateName("P"), source.GetFunctionExpression(source.GetFunctionName("P"))),
+	}
+	if err := GetSMTLIBWriter().WriteScript(mixed, &bytes.Buffer{}); err == nil {
+		t.Error("expected error for inconsistent symbol")
+	}
+}
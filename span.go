@@ -0,0 +1,40 @@
+package logic
+
+// Span is the extent of a particle in reader input, from Start up to but not
+// including End.
+type Span struct {
+	Start Position
+	End Position
+}
+
+// SpanTable records where particles built by a reader came from.  Particles
+// are keyed by identity, so a ParticleSource that shares equal particles
+// will accumulate one span per occurrence.
+type SpanTable struct {
+	spans map[Particle][]Span
+}
+
+func NewSpanTable() *SpanTable {
+	return &SpanTable{spans: make(map[Particle][]Span)}
+}
+
+func (st *SpanTable) Add(p Particle, span Span) {
+	st.spans[p] = append(st.spans[p], span)
+}
+
+// Span returns the first recorded span of p.
+func (st *SpanTable) Span(p Particle) (Span, bool) {
+	spans := st.spans[p]
+	if len(spans) == 0 {
+		return Span{}, false
+	}
+	return spans[0], true
+}
+
+func (st *SpanTable) Spans(p Particle) []Span {
+	return append([]Span{}, st.spans[p]...)
+}
+
+func (st *SpanTable) Len() int {
+	return len(st.spans)
+}
@@ -0,0 +1,208 @@
+package logic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Substitution maps variables, by name, to terms.  Variables not in its
+// domain are left alone.
+type Substitution struct {
+	domain []NamedParticle
+	terms map[string]Particle
+}
+
+func CreateSubstitution() *Substitution {
+	return &Substitution{terms: make(map[string]Particle)}
+}
+
+// Bind maps v to t, replacing any earlier binding; binding v to itself
+// removes it from the domain.  It returns s.
+func (s *Substitution) Bind(v NamedParticle, t Particle) *Substitution {
+	if v.Type() != VARIABLE {
+		panic("substituted particle is not a variable")
+	}
+	if !t.Term() {
+		panic("substitution value is not a term")
+	}
+	name := v.String()
+	if _, ok := s.terms[name]; ok {
+		for i, d := range s.domain {
+			if d.String() == name {
+				s.domain = append(s.domain[:i:i], s.domain[i+1:]...)
+				break
+			}
+		}
+		delete(s.terms, name)
+	}
+	if t.Type() == VARIABLE && t.(NamedParticle).String() == name {
+		return s
+	}
+	s.domain = append(s.domain, v)
+	s.terms[name] = t
+	return s
+}
+
+// Lookup returns the term v is mapped to, if it is in the domain.
+func (s *Substitution) Lookup(v NamedParticle) (Particle, bool) {
+	t, ok := s.terms[v.String()]
+	return t, ok
+}
+
+func (s *Substitution) Len() int {
+	return len(s.domain)
+}
+
+// Domain returns the variables s maps, in the order they were bound.
+func (s *Substitution) Domain() []NamedParticle {
+	return append([]NamedParticle{}, s.domain...)
+}
+
+// Range returns the terms of the domain, in the same order.
+func (s *Substitution) Range() []Particle {
+	r := make([]Particle, len(s.domain))
+	for i, v := range s.domain {
+		r[i] = s.terms[v.String()]
+	}
+	return r
+}
+
+// Restrict returns s with its domain limited to vars.
+func (s *Substitution) Restrict(vars ...NamedParticle) *Substitution {
+	keep := make(map[string]bool)
+	for _, v := range vars {
+		keep[v.String()] = true
+	}
+	r := CreateSubstitution()
+	for _, v := range s.domain {
+		if keep[v.String()] {
+			r.Bind(v, s.terms[v.String()])
+		}
+	}
+	return r
+}
+
+// Compose returns the substitution that applies s and then t, mapping each
+// variable of s's domain to t applied to its term, and the rest of t's
+// domain as t does.
+func (s *Substitution) Compose(t *Substitution) *Substitution {
+	r := CreateSubstitution()
+	for _, v := range s.domain {
+		r.Bind(v, t.Apply(s.terms[v.String()]))
+	}
+	for _, v := range t.domain {
+		if _, ok := s.terms[v.String()]; !ok {
+			r.Bind(v, t.terms[v.String()])
+		}
+	}
+	return r
+}
+
+func (s *Substitution) String() string {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, v := range s.domain {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		GetStandardWriter().Write(v, &b)
+		b.WriteString(" -> ")
+		GetStandardWriter().Write(s.terms[v.String()], &b)
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+// Apply replaces the free occurrences of the variables of s's domain in p,
+// building the result with p.Source().  A quantified variable that would
+// capture a variable of a substituted term is renamed, by appending the
+// smallest number that makes it distinct from the variables free in the
+// quantified particle or the terms substituted into it.
+func (s *Substitution) Apply(p Particle) Particle {
+	if len(s.domain) == 0 {
+		return p
+	}
+	return applySubstitution(p, s.terms)
+}
+
+func applySubstitution(p Particle, terms map[string]Particle) Particle {
+	source := p.Source()
+	switch(p.Type()) {
+		case VARIABLE: {
+			v, ok := p.(NamedParticle)
+			if !ok {
+				return p
+			}
+			t, ok := terms[v.String()]
+			if !ok {
+				return p
+			}
+			if t.Source() != source {
+				return CopyParticle(source, t)
+			}
+			return t
+		}
+		case QUANTIFIED_TERM, QUANTIFIED_PREDICATE: {
+			if lq, ok := p.(*deBruijnQuantified); ok {
+				return lq.substitute(terms)
+			}
+			qp := p.(QuantifiedParticle)
+			v := qp.Variable()
+			inner := make(map[string]Particle)
+			avoid := make(map[string]bool)
+			capture := false
+			for _, fv := range FreeVariables(qp.Argument()) {
+				avoid[fv.String()] = true
+				t, ok := terms[fv.String()]
+				if !ok || fv.String() == v.String() {
+					continue
+				}
+				inner[fv.String()] = t
+				for _, tv := range FreeVariables(t) {
+					avoid[tv.String()] = true
+					if tv.String() == v.String() {
+						capture = true
+					}
+				}
+			}
+			if len(inner) == 0 {
+				return p
+			}
+			if capture {
+				fresh := source.GetVariableNamed(freshName(v.String(), avoid))
+				inner[v.String()] = fresh
+				v = fresh
+			}
+			return source.Get(p.Type(), qp.Quantifier(), v, applySubstitution(qp.Argument(), inner))
+		}
+	}
+	if p.Name() {
+		return p
+	}
+	parts := p.Parts()
+	changed := false
+	for i, part := range parts {
+		if part.Name() {
+			continue
+		}
+		if q := applySubstitution(part, terms); q != part {
+			parts[i] = q
+			changed = true
+		}
+	}
+	if !changed {
+		return p
+	}
+	return source.Get(p.Type(), parts...)
+}
+
+// freshName returns name followed by the smallest positive number that is
+// not in avoid.
+func freshName(name string, avoid map[string]bool) string {
+	for i := 1; ; i++ {
+		fresh := fmt.Sprintf("%s%d", name, i)
+		if !avoid[fresh] {
+			return fresh
+		}
+	}
+}
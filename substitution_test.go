@@ -0,0 +1,96 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func readInfix(t *testing.T, source ParticleSource, text string, term bool) Particle {
+	var p Particle
+	var err error
+	if term {
+		p, err = GetInfixReader().ReadTerm(source, bufio.NewReader(strings.NewReader(text)))
+	} else {
+		p, err = GetInfixReader().ReadPredicate(source, bufio.NewReader(strings.NewReader(text)))
+	}
+	if err != nil {
+		t.Fatalf("%s: %s", text, err.Error())
+	}
+	return p
+}
+
+func writeInfix(p Particle) string {
+	var buf bytes.Buffer
+	GetInfixWriter().Write(p, &buf)
+	return buf.String()
+}
+
+func TestSubstitution(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x, y, z := source.GetVariableNamed("x"), source.GetVariableNamed("y"), source.GetVariableNamed("z")
+	cases := []struct {
+		bindings []string
+		text string
+		result string
+	}{
+		{[]string{"x", "f(y)"}, "P(x, y) & Q(x)", "P(f(y), y) & Q(f(y))"},
+		{[]string{"x", "f(y)"}, "forall x. P(x)", "forall x. P(x)"},
+		{[]string{"x", "f(y)"}, "forall y. P(x, y)", "forall y1. P(f(y), y1)"},
+		{[]string{"x", "g(y, y1)"}, "forall y. P(x, y)", "forall y2. P(g(y, y1), y2)"},
+		{[]string{"x", "y", "y", "x"}, "P(x, y) & exists x. Q(x, y)", "P(y, x) & exists x1. Q(x1, x)"},
+		{[]string{"z", "c()"}, "forall x. P(x, y)", "forall x. P(x, y)"},
+	}
+	for _, c := range cases {
+		s := CreateSubstitution()
+		for i := 0; i < len(c.bindings); i += 2 {
+			s.Bind(source.GetVariableNamed(c.bindings[i]), readInfix(t, source, c.bindings[i+1], true))
+		}
+		p := readInfix(t, source, c.text, false)
+		if result := writeInfix(s.Apply(p)); result != c.result {
+			t.Errorf("%s applied to '%s' gave '%s', expected '%s'", s.String(), c.text, result, c.result)
+		}
+	}
+
+	p := readInfix(t, source, "P(z)", false)
+	if CreateSubstitution().Bind(x, y).Apply(p) != p {
+		t.Errorf("substitution rebuilt a particle it did not change")
+	}
+	is := CreateInterningParticleSource()
+	q := CreateSubstitution().Bind(z, readInfix(t, source, "f(x)", true)).Apply(readInfix(t, is, "P(z)", false))
+	if q.Source() != ParticleSource(is) || q != readInfix(t, is, "P(f(x))", false) {
+		t.Errorf("substitution did not build with the particle's source")
+	}
+}
+
+func TestSubstitutionAlgebra(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x, y, z := source.GetVariableNamed("x"), source.GetVariableNamed("y"), source.GetVariableNamed("z")
+	fy := readInfix(t, source, "f(y)", true)
+	s := CreateSubstitution().Bind(x, fy).Bind(z, z)
+	u := CreateSubstitution().Bind(y, readInfix(t, source, "c()", true)).Bind(x, z)
+	if s.String() != "{$x -> f($y)}" || s.Len() != 1 {
+		t.Errorf("s is %s", s.String())
+	}
+	composed := s.Compose(u)
+	if composed.String() != "{$x -> f(c()), $y -> c()}" {
+		t.Errorf("composed %s", composed.String())
+	}
+	p := readInfix(t, source, "P(x, y, z)", false)
+	if !composed.Apply(p).Equals(u.Apply(s.Apply(p))) {
+		t.Errorf("composition does not apply s then u")
+	}
+	if r := composed.Restrict(y, z); r.String() != "{$y -> c()}" {
+		t.Errorf("restricted to %s", r.String())
+	}
+	if d := names(composed.Domain()); d != "x y" {
+		t.Errorf("domain %s", d)
+	}
+	if r := composed.Range(); len(r) != 2 || writeInfix(r[0]) != "f(c())" {
+		t.Errorf("wrong range")
+	}
+	if v, ok := composed.Lookup(y); !ok || writeInfix(v) != "c()" {
+		t.Errorf("lookup of y failed")
+	}
+}
@@ -0,0 +1,70 @@
+%------------------------------------------------------------------------------
+% Problem  : Dreadbury Mansion
+% Comments : Someone who lives in Dreadbury Mansion killed Aunt Agatha.
+%            Agatha, the butler and Charles live there, and are the only
+%            people who do.  A killer always hates and is never richer than
+%            the victim.  Charles hates no one that Agatha hates.  Agatha
+%            hates everyone except the butler.  The butler hates everyone
+%            not richer than Agatha, and everyone Agatha hates.  No one
+%            hates everyone.  Agatha is not the butler.
+%------------------------------------------------------------------------------
+fof(pel55_1,axiom,
+    ? [X] :
+      ( lives(X)
+      & killed(X,agatha) ) ).
+
+fof(pel55_2_1,axiom,
+    lives(agatha) ).
+
+fof(pel55_2_2,axiom,
+    lives(butler) ).
+
+fof(pel55_2_3,axiom,
+    lives(charles) ).
+
+fof(pel55_3,axiom,
+    ! [X] :
+      ( lives(X)
+     => ( X = agatha
+        | X = butler
+        | X = charles ) ) ).
+
+fof(pel55_4,axiom,
+    ! [X,Y] :
+      ( killed(X,Y)
+     => hates(X,Y) ) ).
+
+fof(pel55_5,axiom,
+    ! [X,Y] :
+      ( killed(X,Y)
+     => ~ richer(X,Y) ) ).
+
+fof(pel55_6,axiom,
+    ! [X] :
+      ( hates(agatha,X)
+     => ~ hates(charles,X) ) ).
+
+fof(pel55_7,axiom,
+    ! [X] :
+      ( X != butler
+     => hates(agatha,X) ) ).
+
+fof(pel55_8,axiom,
+    ! [X] :
+      ( ~ richer(X,agatha)
+     => hates(butler,X) ) ).
+
+fof(pel55_9,axiom,
+    ! [X] :
+      ( hates(agatha,X)
+     => hates(butler,X) ) ).
+
+fof(pel55_10,axiom,
+    ! [X] :
+    ? [Y] : ~ hates(X,Y) ).
+
+fof(pel55_11,axiom,
+    agatha != butler ).
+
+fof(pel55,conjecture,
+    killed(agatha,agatha) ).
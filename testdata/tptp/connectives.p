@@ -0,0 +1,22 @@
+% Every connective of the first-order language, with quoted names and
+% the defined truth values.
+include('Axioms/SET001+0.ax').
+include('Axioms/SET001+1.ax',[member_defn]).
+
+fof(iff,definition,
+    ! [A,B] : ( subset(A,B) <=> ! [X] : ( member(X,A) => member(X,B) ) ) ).
+
+fof(reverse_implication,axiom,
+    ! [X] : ( 'Is empty'(X) <= ~ ? [Y] : member(Y,X) ) ).
+
+fof(xor,axiom,
+    ! [X] : ( 'Is empty'(X) <~> inhabited(X) ) ).
+
+fof(nor_nand,axiom,
+    ( ( p ~| q ) & ( p ~& $true ) ) | $false ).
+
+fof('quoted \'name\'',lemma,
+    'it\'s'(c) | 'back\\slash'(c) ).
+
+fof(1,theorem,
+    ( ( a & b ) & c ) => ( a & b & c ) ).
@@ -0,0 +1,27 @@
+%--------------------------------------------------------------------------
+% Problem  : A group in which every element squares to the identity is
+%            commutative.
+% Comments : Clausal form, equality axioms left to the prover.
+%--------------------------------------------------------------------------
+/* Group axioms: left identity, left inverse, associativity. */
+cnf(left_identity,axiom,
+    multiply(identity,X) = X ).
+
+cnf(left_inverse,axiom,
+    multiply(inverse(X),X) = identity ).
+
+cnf(associativity,axiom,
+    multiply(multiply(X,Y),Z) = multiply(X,multiply(Y,Z)) ).
+
+cnf(squares_to_identity,hypothesis,
+    multiply(X,X) = identity ).
+
+cnf(product_exists,hypothesis,
+    product(a,b,c), inference(definition,[status(thm)],[])).
+
+cnf(product_defn,axiom,
+    ( ~ product(X,Y,Z)
+    | multiply(X,Y) = Z ) ).
+
+cnf(prove_commutative,negated_conjecture,
+    multiply(b,a) != c ).
@@ -0,0 +1,381 @@
+package logic
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// A theory file is a sequence of statements in the standard syntax:
+//
+//	% comment to end of line
+//	include "common/equality.thy"
+//	func f/2, g/1
+//	pred P/1
+//	axiom foo: A$x:P[f($x,g($x))]:
+//	conjecture bar: E$x:P[$x]:
+//
+// Included paths are slash-separated and resolved against the root of the
+// TheoryReader.  Once every included theory is read, declared arities are
+// checked against every formula that uses the symbol, in the including theory
+// and in those it includes.
+
+type FormulaRole int
+const (
+	AXIOM FormulaRole = iota
+	CONJECTURE
+)
+
+func (fr FormulaRole) String() string {
+	switch(fr) {
+		case AXIOM: return "axiom"
+		case CONJECTURE: return "conjecture"
+	}
+	return "<unknown>"
+}
+
+type NamedFormula struct {
+	Role FormulaRole
+	Name string
+	Formula Particle
+	File string
+	Span Span
+}
+
+type Theory struct {
+	File string
+	Includes []*Theory
+	Functions map[string]int
+	Predicates map[string]int
+	Formulas []*NamedFormula
+}
+
+type TheoryReader struct {
+	Root fs.FS
+	loaded map[string]*Theory
+	loading map[string]bool
+	spans map[string]*SpanTable
+	errs []*ParseError
+}
+
+type TheoryWriter struct {
+	Writer LogicWriter
+}
+
+func NewTheory(file string) *Theory {
+	return &Theory{File: file, Functions: make(map[string]int), Predicates: make(map[string]int)}
+}
+
+func GetTheoryReader(root string) *TheoryReader {
+	return &TheoryReader{Root: os.DirFS(root)}
+}
+
+func GetTheoryWriter() *TheoryWriter {
+	return &TheoryWriter{Writer: GetStandardWriter()}
+}
+
+// Visit calls fn for th and each theory it includes, transitively, visiting
+// included theories first and each theory once.
+func (th *Theory) Visit(fn func(*Theory)) {
+	seen := make(map[*Theory]bool)
+	var visit func(t *Theory)
+	visit = func(t *Theory) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		for _, inc := range t.Includes {
+			visit(inc)
+		}
+		fn(t)
+	}
+	visit(th)
+}
+
+// AllFormulas returns the formulas of th and its includes in file order.
+func (th *Theory) AllFormulas() []*NamedFormula {
+	var fs []*NamedFormula
+	th.Visit(func(t *Theory) {
+		fs = append(fs, t.Formulas...)
+	})
+	return fs
+}
+
+func (th *Theory) Formula(name string) *NamedFormula {
+	for _, f := range th.AllFormulas() {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (th *Theory) FunctionArity(name string) (int, bool) {
+	arity, ok := -1, false
+	th.Visit(func(t *Theory) {
+		if a, found := t.Functions[name]; found {
+			arity, ok = a, true
+		}
+	})
+	return arity, ok
+}
+
+func (th *Theory) PredicateArity(name string) (int, bool) {
+	arity, ok := -1, false
+	th.Visit(func(t *Theory) {
+		if a, found := t.Predicates[name]; found {
+			arity, ok = a, true
+		}
+	})
+	return arity, ok
+}
+
+// ReadTheory reads the theory file at name and everything it includes.  All
+// errors found are returned; the theory holds every statement read without
+// error.
+func (tr *TheoryReader) ReadTheory(source ParticleSource, name string) (*Theory, []*ParseError) {
+	tr.loaded = make(map[string]*Theory)
+	tr.loading = make(map[string]bool)
+	tr.spans = make(map[string]*SpanTable)
+	tr.errs = nil
+	th, err := tr.load(source, path.Clean(name))
+	if err != nil {
+		tr.errs = append(tr.errs, &ParseError{Start: Position{Line: 1, Col: 1}, End: Position{Line: 1, Col: 1}, Msg: err.Error(), File: name})
+		return th, tr.errs
+	}
+	tr.checkSignatures(th)
+	return th, tr.errs
+}
+
+func (tr *TheoryReader) load(source ParticleSource, name string) (*Theory, error) {
+	if th, ok := tr.loaded[name]; ok {
+		return th, nil
+	}
+	if tr.loading[name] {
+		return nil, errors.New(fmt.Sprintf("include cycle through '%s'", name))
+	}
+	f, err := tr.Root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tr.loading[name] = true
+	th := tr.read(source, name, bufio.NewReader(f))
+	delete(tr.loading, name)
+	tr.loaded[name] = th
+	return th, nil
+}
+
+func (tr *TheoryReader) read(source ParticleSource, name string, in *bufio.Reader) *Theory {
+	th := NewTheory(name)
+	sr := GetStandardReader()
+	sr.CommentStart = '%'
+	sr.Spans = NewSpanTable()
+	tr.spans[name] = sr.Spans
+	sr.bind(source, in)
+	names := make(map[string]bool)
+	for {
+		sr.NextWS(in)
+		if _, err := in.Peek(1); err != nil {
+			break
+		}
+		sr.depth = 0
+		if pe := tr.readStatement(sr, source, th, names, in); pe != nil {
+			pe.File = name
+			tr.errs = append(tr.errs, pe)
+			sr.SkipFormula(in)
+		}
+	}
+	return th
+}
+
+// readStatement reads a statement into th.  names holds the names of the
+// formulas of th and its includes read so far.
+func (tr *TheoryReader) readStatement(sr *StandardReader, source ParticleSource, th *Theory, names map[string]bool, in *bufio.Reader) (pe *ParseError) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe = sr.asParseError(r)
+		}
+	}()
+	start := sr.cur
+	keyword := sr.NextIdentifier(in)
+	switch(keyword) {
+		case "include": {
+			sr.NextWS(in)
+			pstart := sr.cur
+			name := path.Clean(sr.NextQuoted(in))
+			inc, err := tr.load(source, name)
+			if err != nil {
+				sr.Fail(&ParseError{Start: pstart, End: sr.cur, Msg: err.Error()})
+			}
+			th.Includes = append(th.Includes, inc)
+			for _, f := range inc.AllFormulas() {
+				names[f.Name] = true
+			}
+		}
+		case "func", "pred": {
+			for {
+				sr.NextWS(in)
+				id := sr.NextIdentifier(in)
+				idStart := sr.tokStart
+				sr.NextWS(in)
+				sr.NextString(in, "/")
+				sr.NextWS(in)
+				arity := tr.readArity(sr, in)
+				decls := th.Functions
+				prev, ok := th.FunctionArity(id)
+				if keyword == "pred" {
+					decls = th.Predicates
+					prev, ok = th.PredicateArity(id)
+				}
+				if ok && prev != arity {
+					sr.Fail(&ParseError{Start: idStart, End: sr.cur,
+						Msg: fmt.Sprintf("'%s' redeclared with arity %d (was %d)", id, arity, prev)})
+				}
+				decls[id] = arity
+				sr.NextWS(in)
+				if !sr.TestPeek(in, ',') {
+					break
+				}
+				sr.NextString(in, ",")
+			}
+		}
+		case "axiom", "conjecture": {
+			role := AXIOM
+			if keyword == "conjecture" {
+				role = CONJECTURE
+			}
+			sr.NextWS(in)
+			name := sr.NextIdentifier(in)
+			nameStart := sr.tokStart
+			nameEnd := sr.cur
+			if names[name] {
+				sr.Fail(&ParseError{Start: nameStart, End: nameEnd, Msg: fmt.Sprintf("duplicate formula name '%s'", name)})
+			}
+			sr.NextWS(in)
+			sr.NextString(in, ":")
+			sr.NextWS(in)
+			p := sr.NextPredicate(in)
+			names[name] = true
+			th.Formulas = append(th.Formulas, &NamedFormula{Role: role, Name: name, Formula: p, File: th.File,
+				Span: Span{Start: start, End: sr.cur}})
+		}
+		default: {
+			sr.Fail(&ParseError{Start: sr.tokStart, End: sr.cur,
+				Expected: []string{"include", "func", "pred", "axiom", "conjecture"}, Found: keyword})
+		}
+	}
+	return nil
+}
+
+func (tr *TheoryReader) readArity(sr *StandardReader, in *bufio.Reader) int {
+	start := sr.cur
+	arity := 0
+	digits := 0
+	for {
+		c, err := sr.readRune(in)
+		if err != nil {
+			break
+		}
+		if c < '0' || c > '9' {
+			sr.unreadRune(in)
+			break
+		}
+		arity = arity*10 + int(c-'0')
+		digits += 1
+	}
+	if digits == 0 {
+		sr.ErrorExpected(in, "arity")
+	}
+	if digits > 6 {
+		sr.Fail(&ParseError{Start: start, End: sr.cur, Msg: "arity out of range"})
+	}
+	return arity
+}
+
+// checkSignatures checks the formulas of th and its includes against the
+// arities declared in all of them, dropping each formula that uses a symbol
+// with the wrong number of arguments.
+func (tr *TheoryReader) checkSignatures(th *Theory) {
+	funcs := make(map[string]int)
+	preds := make(map[string]int)
+	th.Visit(func(t *Theory) {
+		for name, arity := range t.Functions {
+			funcs[name] = arity
+		}
+		for name, arity := range t.Predicates {
+			preds[name] = arity
+		}
+	})
+	th.Visit(func(t *Theory) {
+		kept := t.Formulas[:0]
+		for _, f := range t.Formulas {
+			if pe := tr.checkSignature(funcs, preds, tr.spans[t.File], f, f.Formula); pe != nil {
+				pe.File = t.File
+				tr.errs = append(tr.errs, pe)
+				continue
+			}
+			kept = append(kept, f)
+		}
+		t.Formulas = kept
+	})
+}
+
+func (tr *TheoryReader) checkSignature(funcs map[string]int, preds map[string]int, spans *SpanTable, f *NamedFormula, p Particle) *ParseError {
+	if tp, ok := p.(TupleParticle); ok {
+		var arity int
+		var declared bool
+		switch(p.Type()) {
+			case FUNCTION_EXPRESSION: arity, declared = funcs[tp.Head().String()]
+			case ATOMIC_PREDICATE: arity, declared = preds[tp.Head().String()]
+		}
+		if declared && arity != tp.Arity() {
+			span, ok := spans.Span(p)
+			if !ok {
+				span = f.Span
+			}
+			return &ParseError{Start: span.Start, End: span.End,
+				Msg: fmt.Sprintf("'%s' has %d arguments, declared arity is %d", tp.Head().String(), tp.Arity(), arity)}
+		}
+	}
+	for _, part := range p.Parts() {
+		if pe := tr.checkSignature(funcs, preds, spans, f, part); pe != nil {
+			return pe
+		}
+	}
+	return nil
+}
+
+func (tw *TheoryWriter) WriteTheory(th *Theory, out io.Writer) error {
+	w := bufio.NewWriter(out)
+	for _, inc := range th.Includes {
+		fmt.Fprintf(w, "include %s\n", strconv.Quote(inc.File))
+	}
+	tw.writeDeclarations(w, "func", th.Functions)
+	tw.writeDeclarations(w, "pred", th.Predicates)
+	for _, f := range th.Formulas {
+		fmt.Fprintf(w, "%s %s: ", f.Role.String(), QuoteIdentifier(f.Name))
+		if err := tw.Writer.Write(f.Formula, w); err != nil {
+			return err
+		}
+		w.WriteString("\n")
+	}
+	return w.Flush()
+}
+
+func (tw *TheoryWriter) writeDeclarations(w *bufio.Writer, keyword string, arities map[string]int) {
+	names := make([]string, 0, len(arities))
+	for name := range arities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s %s/%d\n", keyword, QuoteIdentifier(name), arities[name])
+	}
+}
@@ -0,0 +1,114 @@
+package logic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTheory(t *testing.T) {
+	root := fstest.MapFS{
+		"base.thy": {Data: []byte(strings.Join([]string{
+			"% shared definitions",
+			"func f/1, g/2",
+			"pred P/1",
+			"axiom refl: A$x:P[$x]:  % trailing comment",
+		}, "\n"))},
+		"main.thy": {Data: []byte(strings.Join([]string{
+			"include \"base.thy\"",
+			"include \"./base.thy\"",
+			"pred Q/2",
+			"",
+			"axiom ax1: A$x:{implies: P[$x],",
+			"                % comment inside a formula",
+			"                Q[$x, f($x)]}:",
+			"conjecture goal: E$y:Q[g($y, $y), $y]:",
+		}, "\n"))},
+	}
+	source := CreateBasicParticleSource()
+	tr := &TheoryReader{Root: root}
+	th, errs := tr.ReadTheory(source, "main.thy")
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if len(th.Includes) != 2 || th.Includes[0] != th.Includes[1] {
+		t.Error("includes not shared")
+	}
+	all := th.AllFormulas()
+	if len(all) != 3 || all[0].Name != "refl" || all[0].File != "base.thy" || all[2].Role != CONJECTURE {
+		t.Errorf("unexpected formulas %v", all)
+	}
+	if a, ok := th.FunctionArity("g"); !ok || a != 2 {
+		t.Error("missing included declaration")
+	}
+
+	var buf bytes.Buffer
+	if err := GetTheoryWriter().WriteTheory(th, &buf); err != nil {
+		t.Fatal(err)
+	}
+	root["copy.thy"] = &fstest.MapFile{Data: buf.Bytes()}
+	copied, errs := tr.ReadTheory(source, "copy.thy")
+	if len(errs) != 0 {
+		t.Fatalf("%s\n%v", buf.String(), errs)
+	}
+	for i, f := range copied.Formulas {
+		if f.Name != th.Formulas[i].Name || f.Role != th.Formulas[i].Role || !f.Formula.Equals(th.Formulas[i].Formula) {
+			t.Errorf("formula %s did not round trip", f.Name)
+		}
+	}
+	if copied.Predicates["Q"] != 2 || len(copied.Functions) != 0 {
+		t.Error("declarations did not round trip")
+	}
+}
+
+func TestTheoryErrors(t *testing.T) {
+	root := fstest.MapFS{
+		"a.thy": {Data: []byte("include \"b.thy\"\naxiom a: P[]\n")},
+		"b.thy": {Data: []byte("include \"a.thy\"\n")},
+		"bad.thy": {Data: []byte(strings.Join([]string{
+			"func f/1",
+			"axiom one: P[f($x, $y)]",
+			"lemma two: P[]",
+			"axiom three: Q[]",
+			"axiom three: R[]",
+			"include \"missing.thy\"",
+			"func f/2",
+			"axiom four: {and: P[],",
+			"  Q[]}",
+		}, "\n"))},
+	}
+	source := CreateBasicParticleSource()
+	tr := &TheoryReader{Root: root}
+	th, errs := tr.ReadTheory(source, "bad.thy")
+	var lines []string
+	for _, e := range errs {
+		if e.File != "bad.thy" {
+			t.Errorf("error in wrong file: %s", e.Error())
+		}
+		lines = append(lines, e.Start.String())
+	}
+	if strings.Join(lines, " ") != "3:1 5:7 6:9 7:6 2:14" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if th.Functions["f"] != 1 || len(th.Formulas) != 2 || th.Formulas[1].Name != "four" {
+		t.Errorf("unexpected formulas %v", th.Formulas)
+	}
+	root["uses.thy"] = &fstest.MapFile{Data: []byte("axiom two: P[$x, $y]\naxiom one: P[$x]\n")}
+	root["declares.thy"] = &fstest.MapFile{Data: []byte("pred P/1\ninclude \"uses.thy\"\naxiom early: Q[$x, $y]\npred Q/1\n")}
+	th, errs = tr.ReadTheory(source, "declares.thy")
+	lines = nil
+	for _, e := range errs {
+		lines = append(lines, e.File+":"+e.Start.String())
+	}
+	if strings.Join(lines, " ") != "uses.thy:1:12 declares.thy:3:14" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+	if all := th.AllFormulas(); len(all) != 1 || all[0].Name != "one" {
+		t.Errorf("unexpected formulas %v", all)
+	}
+	_, errs = tr.ReadTheory(source, "a.thy")
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "include cycle") {
+		t.Errorf("expected include cycle, got %v", errs)
+	}
+}
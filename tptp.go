@@ -0,0 +1,821 @@
+package logic
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TPTP is the exchange syntax of most first-order theorem provers.  The
+// reader and writer handle its untyped first-order (fof) and clause normal
+// form (cnf) languages:
+//
+//	fof(dom, axiom, ! [X] : (p(X) => ? [Y] : (f(Y) = X & 'Sum of'(X,Y) != c))).
+//	cnf(neg, negated_conjecture, ~ p(X) | X = a).
+//
+// Variables are upper-case words and functions and predicates lower-case
+// words or single-quoted names.  ! and ? are the QUANTIFIER names A and E, and
+// the connectives ~ & | => <=> are the standard OPERATOR names.  Chains of &
+// or | are read as one expression with all their arguments.  The remaining
+// connectives are read in terms of those: a <= b as b => a, a <~> b as
+// ~ (a <=> b), a ~| b and a ~& b as negated disjunction and conjunction, and
+// s != t as ~ s = t, which is also how the writer writes it.  Equality is the
+// predicate =, and $true and $false are predicates with no arguments.
+// Clauses are read with their variables free.
+//
+// Includes are recorded but not read, and any selection of formulas they
+// name is ignored; their paths are relative to the TPTP installation rather
+// than to the including file.
+
+const (
+	TPTP_FOF = "fof"
+	TPTP_CNF = "cnf"
+)
+
+// TPTPFormula is an annotated formula, with any annotations after the
+// formula discarded.
+type TPTPFormula struct {
+	Language string
+	Name string
+	Role string
+	Formula Particle
+	Span Span
+}
+
+type TPTPProblem struct {
+	Includes []string
+	Formulas []*TPTPFormula
+}
+
+type TPTPReader struct {
+	StandardReader
+}
+
+type TPTPWriter struct {
+	LogicWriter
+}
+
+var tptpConnectives = map[string]string{
+	"&": OP_AND,
+	"|": OP_OR,
+	"=>": OP_IMPLIES,
+	"<=>": OP_IFF,
+}
+
+var tptpBinary = []string{"&", "|", "=>", "<=>", "<=", "<~>", "~|", "~&"}
+
+func GetTPTPReader() *TPTPReader {
+	tr := TPTPReader{}
+	tr.Chain = &tr
+	tr.CommentStart = '%'
+	tr.cur = Position{Line: 1, Col: 1}
+	return &tr
+}
+
+func GetTPTPWriter() *TPTPWriter {
+	return &TPTPWriter{}
+}
+
+func isTPTPAlpha(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func isTPTPLower(s string) bool {
+	return s != "" && s[0] >= 'a' && s[0] <= 'z' && isTPTPWord(s)
+}
+
+func isTPTPUpper(s string) bool {
+	return s != "" && s[0] >= 'A' && s[0] <= 'Z' && isTPTPWord(s)
+}
+
+func isTPTPWord(s string) bool {
+	for _, r := range s {
+		if !isTPTPAlpha(r) && !(r >= '0' && r <= '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+func (tr *TPTPReader) IdentifierStart(r rune) bool {
+	return isTPTPAlpha(r)
+}
+
+func (tr *TPTPReader) IdentifierPart(r rune) bool {
+	return isTPTPAlpha(r) || r >= '0' && r <= '9' || r == '_'
+}
+
+// ws skips whitespace and both line and block comments.
+func (tr *TPTPReader) ws(in *bufio.Reader) {
+	for {
+		tr.NextWS(in)
+		if tr.peekSymbol(in, "/*") == "" {
+			return
+		}
+		start := tr.cur
+		tr.NextString(in, "/*")
+		for tr.peekSymbol(in, "*/") == "" {
+			if _, err := tr.readRune(in); err != nil {
+				tr.Fail(&ParseError{Start: start, End: tr.cur, Msg: "unterminated comment"})
+			}
+		}
+		tr.NextString(in, "*/")
+	}
+}
+
+// peekSymbol returns the longest of syms that the input starts with, without
+// consuming it.
+func (tr *TPTPReader) peekSymbol(in *bufio.Reader, syms ...string) string {
+	match := ""
+	for _, sym := range syms {
+		if len(sym) <= len(match) {
+			continue
+		}
+		if buf, _ := in.Peek(len(sym)); string(buf) == sym {
+			match = sym
+		}
+	}
+	return match
+}
+
+// nextSingleQuoted reads a single-quoted name, in which only \\ and \' are
+// escapes.
+func (tr *TPTPReader) nextSingleQuoted(in *bufio.Reader) string {
+	start := tr.cur
+	tr.tokStart = start
+	tr.NextString(in, "'")
+	var name []rune
+	for {
+		c, err := tr.readRune(in)
+		if err != nil || c == '\n' {
+			tr.Fail(&ParseError{Start: start, End: tr.cur, Expected: []string{"'"}, Found: string(name)})
+		}
+		if c == '\'' {
+			break
+		}
+		if c == '\\' {
+			if c, err = tr.readRune(in); err != nil || c != '\\' && c != '\'' {
+				tr.Fail(&ParseError{Start: start, End: tr.cur, Msg: "invalid escape in quoted name"})
+			}
+		}
+		name = append(name, c)
+	}
+	if len(name) == 0 {
+		tr.Fail(&ParseError{Start: start, End: tr.cur, Msg: "empty quoted name"})
+	}
+	return string(name)
+}
+
+// nextName reads a word or quoted name, returning whether it was a variable
+// and whether it was quoted.
+func (tr *TPTPReader) nextName(in *bufio.Reader) (string, bool, bool) {
+	tr.ws(in)
+	if tr.TestPeek(in, '\'') {
+		return tr.nextSingleQuoted(in), false, true
+	}
+	if tr.TestPeek(in, '$') {
+		start := tr.cur
+		tr.NextString(in, "$")
+		name := "$" + tr.NextIdentifier(in)
+		tr.tokStart = start
+		return name, false, false
+	}
+	if !tr.PeekIdentifierStart(in) {
+		tr.ErrorExpected(in, "name")
+	}
+	name := tr.NextIdentifier(in)
+	return name, isTPTPUpper(name), false
+}
+
+// nextArguments reads a parenthesized term list, if there is one.
+func (tr *TPTPReader) nextArguments(in *bufio.Reader) []Particle {
+	var args []Particle
+	if !tr.TestPeek(in, '(') {
+		return nil
+	}
+	tr.NextString(in, "(")
+	for {
+		args = append(args, tr.nextTerm(in))
+		tr.ws(in)
+		if tr.TestPeek(in, ')') {
+			break
+		}
+		if !tr.TestPeek(in, ',') {
+			tr.ErrorExpected(in, ",", ")")
+		}
+		tr.NextString(in, ",")
+	}
+	tr.NextString(in, ")")
+	return args
+}
+
+func (tr *TPTPReader) nextTerm(in *bufio.Reader) Particle {
+	tr.ws(in)
+	start := tr.cur
+	name, variable, quoted := tr.nextName(in)
+	nameEnd := tr.cur
+	if variable {
+		v := tr.cSource.GetVariableNamed(name)
+		tr.Record(v, start, nameEnd)
+		return v
+	}
+	if !quoted && strings.HasPrefix(name, "$") {
+		tr.Fail(&ParseError{Start: start, End: nameEnd, Msg: fmt.Sprintf("unsupported defined term '%s'", name)})
+	}
+	head := tr.cSource.GetFunctionName(name)
+	tr.Record(head, start, nameEnd)
+	p := tr.cSource.GetFunctionExpression(head, tr.nextArguments(in)...)
+	tr.Record(p, start, tr.cur)
+	return p
+}
+
+// nextAtom reads an atomic formula: an applied predicate, or an equation or
+// inequation between terms.
+func (tr *TPTPReader) nextAtom(in *bufio.Reader) Particle {
+	source := tr.cSource
+	start := tr.cur
+	name, variable, quoted := tr.nextName(in)
+	nameEnd := tr.cur
+	var lhs Particle
+	var args []Particle
+	if variable {
+		lhs = source.GetVariableNamed(name)
+		tr.Record(lhs, start, nameEnd)
+	} else {
+		args = tr.nextArguments(in)
+	}
+	end := tr.cur
+	tr.ws(in)
+	eq := tr.peekSymbol(in, "=", "!=", "=>")
+	if eq == "=" || eq == "!=" {
+		if lhs == nil {
+			head := source.GetFunctionName(name)
+			tr.Record(head, start, nameEnd)
+			lhs = source.GetFunctionExpression(head, args...)
+			tr.Record(lhs, start, end)
+		}
+		tr.NextString(in, eq)
+		rhs := tr.nextTerm(in)
+		var p Particle = source.GetAtomicPredicate(source.GetPredicateName(PRED_EQUALS), lhs, rhs)
+		tr.Record(p, start, tr.cur)
+		if eq == "!=" {
+			p = source.GetPredicateExpression(source.GetOperator(OP_NOT), p)
+			tr.Record(p, start, tr.cur)
+		}
+		return p
+	}
+	if variable {
+		tr.ErrorExpected(in, "=", "!=")
+	}
+	if !quoted && strings.HasPrefix(name, "$") && (name != "$true" && name != "$false" || len(args) > 0) {
+		tr.Fail(&ParseError{Start: start, End: end, Msg: fmt.Sprintf("unsupported defined predicate '%s'", name)})
+	}
+	head := source.GetPredicateName(name)
+	tr.Record(head, start, nameEnd)
+	p := source.GetAtomicPredicate(head, args...)
+	tr.Record(p, start, end)
+	return p
+}
+
+func (tr *TPTPReader) nextUnit(in *bufio.Reader) Particle {
+	source := tr.cSource
+	tr.ws(in)
+	start := tr.cur
+	switch(tr.peekSymbol(in, "~", "!", "?", "(", "!=")) {
+		case "~": {
+			tr.NextString(in, "~")
+			arg := tr.nextUnit(in)
+			p := source.GetPredicateExpression(source.GetOperator(OP_NOT), arg)
+			tr.Record(p, start, tr.cur)
+			return p
+		}
+		case "!", "?": {
+			q := QUANT_FORALL
+			if tr.TestPeek(in, '?') {
+				q = QUANT_EXISTS
+			}
+			tr.readRune(in)
+			quant := source.GetQuantifier(q)
+			tr.Record(quant, start, tr.cur)
+			tr.ws(in)
+			tr.NextString(in, "[")
+			var vars []NamedParticle
+			for {
+				tr.ws(in)
+				vstart := tr.cur
+				name, variable, _ := tr.nextName(in)
+				if !variable {
+					tr.Fail(&ParseError{Start: vstart, End: tr.cur, Expected: []string{"variable"}, Found: name})
+				}
+				v := source.GetVariableNamed(name)
+				tr.Record(v, vstart, tr.cur)
+				vars = append(vars, v)
+				tr.ws(in)
+				if tr.TestPeek(in, ']') {
+					break
+				}
+				if !tr.TestPeek(in, ',') {
+					tr.ErrorExpected(in, ",", "]")
+				}
+				tr.NextString(in, ",")
+			}
+			tr.NextString(in, "]")
+			tr.ws(in)
+			tr.NextString(in, ":")
+			p := tr.nextUnit(in)
+			for i := len(vars)-1; i >= 0; i-- {
+				p = source.GetQuantifiedPredicate(quant, vars[i], p)
+				tr.Record(p, start, tr.cur)
+			}
+			return p
+		}
+		case "(": {
+			tr.NextString(in, "(")
+			p := tr.nextFormula(in)
+			tr.ws(in)
+			if !tr.TestPeek(in, ')') {
+				tr.ErrorExpected(in, ")")
+			}
+			tr.NextString(in, ")")
+			return p
+		}
+	}
+	return tr.nextAtom(in)
+}
+
+// nextFormula reads a unit formula, a chain of & or | between units, or a
+// single binary connective between two units.
+func (tr *TPTPReader) nextFormula(in *bufio.Reader) Particle {
+	source := tr.cSource
+	tr.ws(in)
+	start := tr.cur
+	args := []Particle{tr.nextUnit(in)}
+	tr.ws(in)
+	conn := tr.peekSymbol(in, tptpBinary...)
+	if conn == "" {
+		return args[0]
+	}
+	for {
+		tr.NextString(in, conn)
+		args = append(args, tr.nextUnit(in))
+		tr.ws(in)
+		next := tr.peekSymbol(in, tptpBinary...)
+		if next == "" {
+			break
+		}
+		if next != conn || conn != "&" && conn != "|" {
+			tr.Fail(&ParseError{Start: tr.cur, End: tr.cur, Found: next,
+				Msg: fmt.Sprintf("'%s' after '%s' needs parentheses", next, conn)})
+		}
+	}
+	var p Particle
+	switch(conn) {
+		case "<=": p = source.GetPredicateExpression(source.GetOperator(OP_IMPLIES), args[1], args[0])
+		case "<~>": p = source.GetPredicateExpression(source.GetOperator(OP_IFF), args...)
+		case "~|": p = source.GetPredicateExpression(source.GetOperator(OP_OR), args...)
+		case "~&": p = source.GetPredicateExpression(source.GetOperator(OP_AND), args...)
+		default: p = source.GetPredicateExpression(source.GetOperator(tptpConnectives[conn]), args...)
+	}
+	switch(conn) {
+		case "<~>", "~|", "~&": {
+			tr.Record(p, start, tr.cur)
+			p = source.GetPredicateExpression(source.GetOperator(OP_NOT), p)
+		}
+	}
+	tr.Record(p, start, tr.cur)
+	return p
+}
+
+// isTPTPLiteral reports whether p is an atom, a negated atom, or a clause of
+// them if clause is set.
+func isTPTPLiteral(p Particle, clause bool) bool {
+	switch(p.Type()) {
+		case ATOMIC_PREDICATE: return true
+		case PREDICATE_EXPRESSION: {
+			tp := p.(TupleParticle)
+			switch(tp.Head().String()) {
+				case OP_NOT: return tp.Arity() == 1 && tp.Argument(0).Type() == ATOMIC_PREDICATE
+				case OP_OR: {
+					if !clause || tp.Arity() < 2 {
+						return false
+					}
+					for _, arg := range tp.Arguments() {
+						if !isTPTPLiteral(arg, false) {
+							return false
+						}
+					}
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (tr *TPTPReader) ReadTerm(source ParticleSource, in *bufio.Reader) (rp Particle, re error) {
+	defer tr.recoverError(&rp, &re)
+	tr.bind(source, in)
+	return tr.nextTerm(in), nil
+}
+
+func (tr *TPTPReader) ReadPredicate(source ParticleSource, in *bufio.Reader) (rp Particle, re error) {
+	defer tr.recoverError(&rp, &re)
+	tr.bind(source, in)
+	return tr.nextFormula(in), nil
+}
+
+func (tr *TPTPReader) ReadParticle(source ParticleSource, ptype ParticleType, in *bufio.Reader) (rp Particle, re error) {
+	switch(ptype) {
+		case VARIABLE, FUNCTION_EXPRESSION: rp, re = tr.ReadTerm(source, in)
+		case ATOMIC_PREDICATE, PREDICATE_EXPRESSION, QUANTIFIED_PREDICATE: rp, re = tr.ReadPredicate(source, in)
+		case PREDICATE_COMPREHENSION, QUANTIFIED_TERM: {
+			return nil, errors.New(fmt.Sprintf("TPTP has no syntax for %s", ptype.String()))
+		}
+		default: {
+			return tr.StandardReader.ReadParticle(source, ptype, in)
+		}
+	}
+	if re == nil && rp.Type() != ptype {
+		return nil, errors.New(fmt.Sprintf("expected %s (found %s)", ptype.String(), rp.Type().String()))
+	}
+	return rp, re
+}
+
+// ReadProblem reads a problem file of annotated formulas and includes.  All
+// errors found are returned; the problem holds every statement read without
+// error.
+func (tr *TPTPReader) ReadProblem(source ParticleSource, in *bufio.Reader) (*TPTPProblem, []*ParseError) {
+	pr := &TPTPProblem{}
+	var errs []*ParseError
+	tr.Spans = NewSpanTable()
+	tr.bind(source, in)
+	for {
+		if pe := tr.readStatement(pr, in); pe != nil {
+			errs = append(errs, pe)
+			tr.skipStatement(in)
+			continue
+		}
+		if _, err := in.Peek(1); err != nil {
+			break
+		}
+	}
+	return pr, errs
+}
+
+func (tr *TPTPReader) readStatement(pr *TPTPProblem, in *bufio.Reader) (pe *ParseError) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe = tr.asParseError(r)
+		}
+	}()
+	tr.ws(in)
+	if _, err := in.Peek(1); err != nil {
+		return nil
+	}
+	start := tr.cur
+	var f *TPTPFormula
+	var inc *string
+	keyword := tr.NextIdentifier(in)
+	keywordStart := tr.tokStart
+	tr.ws(in)
+	tr.NextString(in, "(")
+	switch(keyword) {
+		case "include": {
+			tr.ws(in)
+			name := tr.nextSingleQuoted(in)
+			inc = &name
+			tr.ws(in)
+			if tr.TestPeek(in, ',') {
+				tr.NextString(in, ",")
+				tr.skipAnnotations(in)
+			}
+		}
+		case TPTP_FOF, TPTP_CNF: {
+			tr.ws(in)
+			name := tr.nextFormulaName(in)
+			tr.ws(in)
+			tr.NextString(in, ",")
+			tr.ws(in)
+			role := tr.NextIdentifier(in)
+			tr.ws(in)
+			tr.NextString(in, ",")
+			tr.ws(in)
+			fstart := tr.cur
+			p := tr.nextFormula(in)
+			if keyword == TPTP_CNF && !isTPTPLiteral(p, true) {
+				tr.Fail(&ParseError{Start: fstart, End: tr.cur, Msg: "cnf formula is not a clause"})
+			}
+			tr.ws(in)
+			if tr.TestPeek(in, ',') {
+				tr.NextString(in, ",")
+				tr.skipAnnotations(in)
+			}
+			f = &TPTPFormula{Language: keyword, Name: name, Role: role, Formula: p}
+		}
+		default: {
+			tr.Fail(&ParseError{Start: keywordStart, End: tr.cur, Expected: []string{"include", TPTP_FOF, TPTP_CNF}, Found: keyword})
+		}
+	}
+	tr.ws(in)
+	if !tr.TestPeek(in, ')') {
+		tr.ErrorExpected(in, ")")
+	}
+	tr.NextString(in, ")")
+	tr.ws(in)
+	tr.NextString(in, ".")
+	if inc != nil {
+		pr.Includes = append(pr.Includes, *inc)
+	}
+	if f != nil {
+		f.Span = Span{Start: start, End: tr.cur}
+		pr.Formulas = append(pr.Formulas, f)
+	}
+	return nil
+}
+
+// nextFormulaName reads the name of an annotated formula: a word, an
+// integer or a quoted name.
+func (tr *TPTPReader) nextFormulaName(in *bufio.Reader) string {
+	if tr.TestPeek(in, '\'') {
+		return tr.nextSingleQuoted(in)
+	}
+	tr.tokStart = tr.cur
+	var name []rune
+	for {
+		c, err := tr.readRune(in)
+		if err != nil {
+			break
+		}
+		if !tr.IdentifierPart(c) {
+			tr.unreadRune(in)
+			break
+		}
+		name = append(name, c)
+	}
+	if len(name) == 0 {
+		tr.ErrorExpected(in, "name")
+	}
+	return string(name)
+}
+
+// skipAnnotations reads up to the ')' closing the statement, skipping nested
+// brackets and quoted text.
+func (tr *TPTPReader) skipAnnotations(in *bufio.Reader) {
+	depth := 0
+	for {
+		tr.ws(in)
+		if depth == 0 && tr.TestPeek(in, ')') {
+			return
+		}
+		if tr.TestPeek(in, '\'') {
+			tr.nextSingleQuoted(in)
+			continue
+		}
+		if tr.TestPeek(in, '"') {
+			tr.NextQuoted(in)
+			continue
+		}
+		c, err := tr.readRune(in)
+		if err != nil {
+			tr.ErrorExpected(in, ")")
+		}
+		switch(c) {
+			case '(', '[': depth += 1
+			case ')', ']': depth -= 1
+		}
+	}
+}
+
+// skipStatement reads past the '.' ending a statement after an error.
+func (tr *TPTPReader) skipStatement(in *bufio.Reader) {
+	quoted := false
+	for {
+		c, err := tr.readRune(in)
+		if err != nil {
+			break
+		}
+		switch {
+			case quoted && c == '\\': tr.readRune(in)
+			case c == '\'': quoted = !quoted
+			case c == '.' && !quoted: {
+				if next, err := in.Peek(1); err != nil || strings.ContainsRune(" \t\r\n%", rune(next[0])) {
+					tr.reset()
+					return
+				}
+			}
+		}
+	}
+	tr.reset()
+}
+
+func (tw *TPTPWriter) Write(p Particle, out io.Writer) error {
+	var b strings.Builder
+	var err error
+	switch(p.Type()) {
+		case VARIABLE, FUNCTION_EXPRESSION: err = tw.writeTerm(&b, p)
+		default: err = tw.writeFormula(&b, p)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, b.String())
+	return err
+}
+
+// WriteProblem writes the includes of pr followed by its formulas, one
+// statement to a line.
+func (tw *TPTPWriter) WriteProblem(pr *TPTPProblem, out io.Writer) error {
+	var b strings.Builder
+	for _, inc := range pr.Includes {
+		b.WriteString("include(")
+		b.WriteString(quoteTPTP(inc))
+		b.WriteString(").\n")
+	}
+	for _, f := range pr.Formulas {
+		if f.Language != TPTP_FOF && f.Language != TPTP_CNF {
+			return errors.New(fmt.Sprintf("unknown TPTP language '%s'", f.Language))
+		}
+		if f.Language == TPTP_CNF && !isTPTPLiteral(f.Formula, true) {
+			return errors.New(fmt.Sprintf("formula '%s' is not a clause", f.Name))
+		}
+		if !isTPTPLower(f.Role) {
+			return errors.New(fmt.Sprintf("invalid TPTP role '%s'", f.Role))
+		}
+		name := tptpName(f.Name)
+		if f.Name != "" && strings.Trim(f.Name, "0123456789") == "" {
+			name = f.Name
+		}
+		fmt.Fprintf(&b, "%s(%s, %s, ", f.Language, name, f.Role)
+		if err := tw.writeFormula(&b, f.Formula); err != nil {
+			return err
+		}
+		b.WriteString(").\n")
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func quoteTPTP(name string) string {
+	return "'" + strings.NewReplacer("\\", "\\\\", "'", "\\'").Replace(name) + "'"
+}
+
+func tptpName(name string) string {
+	if isTPTPLower(name) {
+		return name
+	}
+	return quoteTPTP(name)
+}
+
+func (tw *TPTPWriter) writeTerm(b *strings.Builder, p Particle) error {
+	switch(p.Type()) {
+		case VARIABLE: {
+			name := p.(NamedParticle).String()
+			if !isTPTPUpper(name) {
+				return errors.New(fmt.Sprintf("cannot write variable '%s' in TPTP", name))
+			}
+			b.WriteString(name)
+			return nil
+		}
+		case FUNCTION_EXPRESSION: {
+			tp := p.(TupleParticle)
+			if tp.Head().String() == "" {
+				return errors.New("cannot write empty function name in TPTP")
+			}
+			b.WriteString(tptpName(tp.Head().String()))
+			return tw.writeArguments(b, tp.Arguments())
+		}
+	}
+	return errors.New(fmt.Sprintf("cannot write %s as a TPTP term", p.Type().String()))
+}
+
+func (tw *TPTPWriter) writeArguments(b *strings.Builder, args []Particle) error {
+	if len(args) == 0 {
+		return nil
+	}
+	b.WriteString("(")
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		if err := tw.writeTerm(b, arg); err != nil {
+			return err
+		}
+	}
+	b.WriteString(")")
+	return nil
+}
+
+// connective returns the TPTP symbol for a binary predicate expression.
+func (tw *TPTPWriter) connective(p Particle) string {
+	if p.Type() != PREDICATE_EXPRESSION {
+		return ""
+	}
+	tp := p.(TupleParticle)
+	for sym, name := range tptpConnectives {
+		if tp.Head().String() != name {
+			continue
+		}
+		if tp.Arity() == 2 || tp.Arity() > 2 && (sym == "&" || sym == "|") {
+			return sym
+		}
+	}
+	return ""
+}
+
+func (tw *TPTPWriter) writeFormula(b *strings.Builder, p Particle) error {
+	conn := tw.connective(p)
+	if conn == "" {
+		return tw.writeUnit(b, p)
+	}
+	for i, arg := range p.(TupleParticle).Arguments() {
+		if i > 0 {
+			b.WriteString(" ")
+			b.WriteString(conn)
+			b.WriteString(" ")
+		}
+		if err := tw.writeUnit(b, arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tw *TPTPWriter) writeUnit(b *strings.Builder, p Particle) error {
+	if tw.connective(p) != "" {
+		b.WriteString("(")
+		if err := tw.writeFormula(b, p); err != nil {
+			return err
+		}
+		b.WriteString(")")
+		return nil
+	}
+	switch(p.Type()) {
+		case ATOMIC_PREDICATE: {
+			tp := p.(TupleParticle)
+			name := tp.Head().String()
+			if name == PRED_EQUALS && tp.Arity() == 2 {
+				return tw.writeEquation(b, tp, "=")
+			}
+			switch {
+				case name == "$true" || name == "$false": {
+					if tp.Arity() > 0 {
+						return errors.New(fmt.Sprintf("cannot write '%s' with arguments in TPTP", name))
+					}
+					b.WriteString(name)
+					return nil
+				}
+				case name == "": return errors.New("cannot write empty predicate name in TPTP")
+			}
+			b.WriteString(tptpName(name))
+			return tw.writeArguments(b, tp.Arguments())
+		}
+		case PREDICATE_EXPRESSION: {
+			tp := p.(TupleParticle)
+			if tp.Head().String() != OP_NOT || tp.Arity() != 1 {
+				return errors.New(fmt.Sprintf("cannot write operator '%s' with %d arguments in TPTP", tp.Head().String(), tp.Arity()))
+			}
+			if eq, ok := tp.Argument(0).(TupleParticle); ok && eq.Type() == ATOMIC_PREDICATE && eq.Head().String() == PRED_EQUALS && eq.Arity() == 2 {
+				return tw.writeEquation(b, eq, "!=")
+			}
+			b.WriteString("~ ")
+			return tw.writeUnit(b, tp.Argument(0))
+		}
+		case QUANTIFIED_PREDICATE: {
+			qp := p.(QuantifiedParticle)
+			q := qp.Quantifier().String()
+			switch(q) {
+				case QUANT_FORALL: b.WriteString("! [")
+				case QUANT_EXISTS: b.WriteString("? [")
+				default: return errors.New(fmt.Sprintf("cannot write quantifier '%s' in TPTP", q))
+			}
+			for i := 0; ; i++ {
+				if i > 0 {
+					b.WriteString(",")
+				}
+				if err := tw.writeTerm(b, qp.Variable()); err != nil {
+					return err
+				}
+				next, ok := qp.Argument().(QuantifiedParticle)
+				if !ok || next.Type() != QUANTIFIED_PREDICATE || next.Quantifier().String() != q {
+					break
+				}
+				qp = next
+			}
+			b.WriteString("] : ")
+			return tw.writeUnit(b, qp.Argument())
+		}
+	}
+	return errors.New(fmt.Sprintf("cannot write %s as a TPTP formula", p.Type().String()))
+}
+
+func (tw *TPTPWriter) writeEquation(b *strings.Builder, tp TupleParticle, sym string) error {
+	if err := tw.writeTerm(b, tp.Argument(0)); err != nil {
+		return err
+	}
+	b.WriteString(" ")
+	b.WriteString(sym)
+	b.WriteString(" ")
+	return tw.writeTerm(b, tp.Argument(1))
+}
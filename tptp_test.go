@@ -0,0 +1,145 @@
+package logic
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readTPTPFile(t *testing.T, source ParticleSource, file string) *TPTPProblem {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	pr, errs := GetTPTPReader().ReadProblem(source, bufio.NewReader(f))
+	for _, pe := range errs {
+		pe.File = file
+		t.Error(pe)
+	}
+	return pr
+}
+
+func TestTPTPProblems(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "tptp", "*.p"))
+	if err != nil || len(files) == 0 {
+		t.Fatal("no sample problems")
+	}
+	counts := map[string]int{"agatha.p": 14, "groups.p": 7, "connectives.p": 6}
+	for _, file := range files {
+		source := CreateBasicParticleSource()
+		pr := readTPTPFile(t, source, file)
+		if len(pr.Formulas) != counts[filepath.Base(file)] {
+			t.Errorf("%s: read %d formulas", file, len(pr.Formulas))
+		}
+		var buf bytes.Buffer
+		if err := GetTPTPWriter().WriteProblem(pr, &buf); err != nil {
+			t.Fatalf("%s: %s", file, err.Error())
+		}
+		text := buf.String()
+		rpr, errs := GetTPTPReader().ReadProblem(source, bufio.NewReader(strings.NewReader(text)))
+		if len(errs) > 0 {
+			t.Fatalf("%s: rereading: %s\n%s", file, errs[0].Error(), text)
+		}
+		if strings.Join(rpr.Includes, ",") != strings.Join(pr.Includes, ",") || len(rpr.Formulas) != len(pr.Formulas) {
+			t.Fatalf("%s: problem did not round trip", file)
+		}
+		for i, f := range pr.Formulas {
+			rf := rpr.Formulas[i]
+			if rf.Language != f.Language || rf.Name != f.Name || rf.Role != f.Role || !rf.Formula.Equals(f.Formula) {
+				t.Errorf("%s: formula %s did not round trip", file, f.Name)
+			}
+		}
+		buf.Reset()
+		GetTPTPWriter().WriteProblem(rpr, &buf)
+		if buf.String() != text {
+			t.Errorf("%s: rewriting changed the text", file)
+		}
+	}
+}
+
+func TestTPTPFormulas(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x := source.GetVariableNamed("X")
+	y := source.GetVariableNamed("Y")
+	butler := source.GetFunctionExpression(source.GetFunctionName("butler"))
+	empty := source.GetPredicateName("Is empty")
+	not := func(p Particle) Particle {
+		return source.GetPredicateExpression(source.GetOperator(OP_NOT), p)
+	}
+	pr := readTPTPFile(t, source, filepath.Join("testdata", "tptp", "agatha.p"))
+	expected := source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), x,
+		source.GetPredicateExpression(source.GetOperator(OP_IMPLIES),
+			not(source.GetAtomicPredicate(source.GetPredicateName(PRED_EQUALS), x, butler)),
+			source.GetAtomicPredicate(source.GetPredicateName("hates"), source.GetFunctionExpression(source.GetFunctionName("agatha")), x)))
+	if f := pr.Formulas[8]; f.Name != "pel55_7" || f.Role != "axiom" || !f.Formula.Equals(expected) {
+		t.Errorf("read %s incorrectly", f.Name)
+	}
+	pr = readTPTPFile(t, source, filepath.Join("testdata", "tptp", "connectives.p"))
+	if strings.Join(pr.Includes, ",") != "Axioms/SET001+0.ax,Axioms/SET001+1.ax" {
+		t.Errorf("read includes %v", pr.Includes)
+	}
+	expected = source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_FORALL), x,
+		source.GetPredicateExpression(source.GetOperator(OP_IMPLIES),
+			not(source.GetQuantifiedPredicate(source.GetQuantifier(QUANT_EXISTS), y,
+				source.GetAtomicPredicate(source.GetPredicateName("member"), y, x))),
+			source.GetAtomicPredicate(empty, x)))
+	if !pr.Formulas[1].Formula.Equals(expected) {
+		t.Error("read reverse implication incorrectly")
+	}
+	if pr.Formulas[4].Name != "quoted 'name'" || pr.Formulas[5].Name != "1" {
+		t.Errorf("read names %s, %s", pr.Formulas[4].Name, pr.Formulas[5].Name)
+	}
+	var buf bytes.Buffer
+	GetTPTPWriter().Write(pr.Formulas[2].Formula, &buf)
+	if buf.String() != "! [X] : ~ ('Is empty'(X) <=> inhabited(X))" {
+		t.Errorf("wrote %s", buf.String())
+	}
+	dollar := source.GetAtomicPredicate(source.GetPredicateName("$p"), source.GetFunctionExpression(source.GetFunctionName("$f"), x))
+	buf.Reset()
+	GetTPTPWriter().Write(dollar, &buf)
+	if p, err := GetTPTPReader().ReadPredicate(source, bufio.NewReader(&buf)); err != nil || !p.Equals(dollar) {
+		t.Errorf("quoted $ names did not round trip: %v", err)
+	}
+}
+
+func TestTPTPErrors(t *testing.T) {
+	source := CreateBasicParticleSource()
+	for _, src := range []string{
+		"a => b => c",
+		"a & b | c",
+		"! [x] : p(x)",
+		"X",
+		"p(X",
+		"$distinct(a,b)",
+	} {
+		if _, err := GetTPTPReader().ReadPredicate(source, bufio.NewReader(strings.NewReader(src))); err == nil {
+			t.Errorf("expected error reading %s", src)
+		}
+	}
+	problem := "fof(a, axiom, p).\ncnf(b, axiom, ! [X] : p(X)).\nfof(c, axiom, p & ).\nfof(d, conjecture, q('x.y')).\n"
+	pr, errs := GetTPTPReader().ReadProblem(source, bufio.NewReader(strings.NewReader(problem)))
+	if len(errs) != 2 || len(pr.Formulas) != 2 || pr.Formulas[1].Name != "d" {
+		t.Errorf("read %d formulas with %d errors", len(pr.Formulas), len(errs))
+	} else if errs[0].Start.Line != 2 || errs[1].Start.Line != 3 {
+		t.Errorf("errors at %s, %s", errs[0].Start.String(), errs[1].Start.String())
+	}
+	pr, errs = GetTPTPReader().ReadProblem(source, bufio.NewReader(strings.NewReader("include('a.p' junk).\nfof(a, axiom, p).\n")))
+	if len(errs) != 1 || len(pr.Includes) != 0 || len(pr.Formulas) != 1 {
+		t.Errorf("read %d includes and %d formulas with %d errors", len(pr.Includes), len(pr.Formulas), len(errs))
+	}
+	x := source.GetVariableNamed("x")
+	for _, p := range []Particle{
+		source.GetAtomicPredicate(source.GetPredicateName("p"), x),
+		source.GetQuantifiedPredicate(source.GetQuantifier("I"), source.GetVariableNamed("X"), source.GetAtomicPredicate(source.GetPredicateName("p"))),
+		source.GetPredicateExpression(source.GetOperator(OP_IMPLIES), source.GetAtomicPredicate(source.GetPredicateName("p"))),
+		source.GetPredicateComprehension(source.GetOperator(OP_AND)),
+	} {
+		if err := GetTPTPWriter().Write(p, &bytes.Buffer{}); err == nil {
+			t.Errorf("expected error writing %s particle", p.Type().String())
+		}
+	}
+}
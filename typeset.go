@@ -0,0 +1,234 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// LaTeXWriter and MathMLWriter typeset particles in the infix syntax, with
+// parentheses placed by the precedences of Table.  The symbols for operators
+// and binary predicates in the table, and for quantifiers, are looked up by
+// name in Symbols and Quantifiers; operators without one are written with
+// their infix symbol, and quantifiers without one cannot be written.  Other
+// operators are written in the braced form, as in the infix syntax.
+//
+// LaTeX is written for math mode:
+//
+//	\forall x.\, Foo(x) \rightarrow x = y
+//
+// MathML is written as a presentation-markup math element, with an mrow for
+// each compound expression.
+
+type LaTeXWriter struct {
+	LogicWriter
+	Table *InfixTable
+	Symbols map[string]string
+	Quantifiers map[string]string
+}
+
+type MathMLWriter struct {
+	LogicWriter
+	Table *InfixTable
+	Symbols map[string]string
+	Quantifiers map[string]string
+	Display bool
+}
+
+func GetLaTeXWriter() *LaTeXWriter {
+	return &LaTeXWriter{
+		Table: DefaultInfixTable(),
+		Symbols: map[string]string{
+			OP_NOT: "\\neg",
+			OP_AND: "\\land",
+			OP_OR: "\\lor",
+			OP_IMPLIES: "\\rightarrow",
+			OP_IFF: "\\leftrightarrow",
+			PRED_EQUALS: "=",
+		},
+		Quantifiers: map[string]string{
+			QUANT_FORALL: "\\forall",
+			QUANT_EXISTS: "\\exists",
+		},
+	}
+}
+
+func GetMathMLWriter() *MathMLWriter {
+	mw := &MathMLWriter{Table: DefaultInfixTable(), Symbols: make(map[string]string), Quantifiers: make(map[string]string)}
+	for name, g := range standardGlyphs {
+		if _, ok := mw.Table.Quantifiers[name]; ok {
+			mw.Quantifiers[name] = string(g)
+		} else {
+			mw.Symbols[name] = string(g)
+		}
+	}
+	mw.Symbols[PRED_EQUALS] = "="
+	return mw
+}
+
+var latexEscapes = strings.NewReplacer(
+	"\\", "\\backslash{}", "{", "\\{", "}", "\\}", "$", "\\$", "&", "\\&", "#", "\\#",
+	"%", "\\%", "_", "\\_", "^", "\\hat{}", "~", "\\sim{}", " ", "\\ ")
+
+func (lw *LaTeXWriter) Write(p Particle, out io.Writer) error {
+	if p.Name() {
+		_, err := io.WriteString(out, latexEscapes.Replace(p.(Name).String()))
+		return err
+	}
+	return emitInfix(lw.Table, lw, p, out)
+}
+
+func (lw *LaTeXWriter) symbol(op *InfixOperator) string {
+	if sym, ok := lw.Symbols[op.Name]; ok {
+		return sym
+	}
+	return latexEscapes.Replace(op.Symbol)
+}
+
+func (lw *LaTeXWriter) name(b *strings.Builder, name string, ptype ParticleType) error {
+	b.WriteString(latexEscapes.Replace(name))
+	return nil
+}
+
+func (lw *LaTeXWriter) braceHead(b *strings.Builder, name string, op *InfixOperator) error {
+	if op != nil {
+		b.WriteString(lw.symbol(op))
+		return nil
+	}
+	return lw.name(b, name, OPERATOR)
+}
+
+// A control word is separated from a following letter by a space.
+func (lw *LaTeXWriter) prefix(b *strings.Builder, op *InfixOperator) {
+	sym := lw.symbol(op)
+	b.WriteString(sym)
+	if r, _ := utf8.DecodeLastRuneInString(sym); r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+		b.WriteString(" ")
+	}
+}
+
+func (lw *LaTeXWriter) infix(b *strings.Builder, op *InfixOperator) {
+	b.WriteString(" ")
+	b.WriteString(lw.symbol(op))
+	b.WriteString(" ")
+}
+
+func (lw *LaTeXWriter) quantifier(b *strings.Builder, q string, vars []string) error {
+	sym, ok := lw.Quantifiers[q]
+	if !ok {
+		return errors.New(fmt.Sprintf("no LaTeX symbol for quantifier '%s'", q))
+	}
+	b.WriteString(sym)
+	b.WriteString(" ")
+	for i, v := range vars {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		lw.name(b, v, VARIABLE)
+	}
+	b.WriteString(".\\, ")
+	return nil
+}
+
+func (lw *LaTeXWriter) punct(b *strings.Builder, tok string) {
+	switch(tok) {
+		case "{", "}": b.WriteString("\\" + tok)
+		case ",": b.WriteString(", ")
+		case " ": b.WriteString("\\ ")
+		case "apply":
+		default: b.WriteString(tok)
+	}
+}
+
+func (lw *LaTeXWriter) begin(b *strings.Builder) {}
+
+func (lw *LaTeXWriter) end(b *strings.Builder) {}
+
+func (mw *MathMLWriter) Write(p Particle, out io.Writer) error {
+	var b strings.Builder
+	if mw.Display {
+		b.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML" display="block">`)
+	} else {
+		b.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML">`)
+	}
+	if p.Name() {
+		mw.name(&b, p.(Name).String(), p.Type())
+	} else if err := emitInfix(mw.Table, mw, p, &b); err != nil {
+		return err
+	}
+	b.WriteString("</math>")
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+func (mw *MathMLWriter) mo(b *strings.Builder, sym string) {
+	b.WriteString("<mo>")
+	b.WriteString(html.EscapeString(sym))
+	b.WriteString("</mo>")
+}
+
+func (mw *MathMLWriter) symbol(op *InfixOperator) string {
+	if sym, ok := mw.Symbols[op.Name]; ok {
+		return sym
+	}
+	return op.Symbol
+}
+
+func (mw *MathMLWriter) name(b *strings.Builder, name string, ptype ParticleType) error {
+	b.WriteString("<mi>")
+	b.WriteString(html.EscapeString(name))
+	b.WriteString("</mi>")
+	return nil
+}
+
+func (mw *MathMLWriter) braceHead(b *strings.Builder, name string, op *InfixOperator) error {
+	if op != nil {
+		mw.mo(b, mw.symbol(op))
+		return nil
+	}
+	return mw.name(b, name, OPERATOR)
+}
+
+func (mw *MathMLWriter) prefix(b *strings.Builder, op *InfixOperator) {
+	mw.mo(b, mw.symbol(op))
+}
+
+func (mw *MathMLWriter) infix(b *strings.Builder, op *InfixOperator) {
+	mw.mo(b, mw.symbol(op))
+}
+
+func (mw *MathMLWriter) quantifier(b *strings.Builder, q string, vars []string) error {
+	sym, ok := mw.Quantifiers[q]
+	if !ok {
+		return errors.New(fmt.Sprintf("no MathML symbol for quantifier '%s'", q))
+	}
+	mw.mo(b, sym)
+	for i, v := range vars {
+		if i > 0 {
+			mw.mo(b, ",")
+		}
+		mw.name(b, v, VARIABLE)
+	}
+	mw.mo(b, ".")
+	return nil
+}
+
+// The function application operator, U+2061, is invisible.
+func (mw *MathMLWriter) punct(b *strings.Builder, tok string) {
+	switch(tok) {
+		case "apply": mw.mo(b, "\u2061")
+		case " ":
+		default: mw.mo(b, tok)
+	}
+}
+
+func (mw *MathMLWriter) begin(b *strings.Builder) {
+	b.WriteString("<mrow>")
+}
+
+func (mw *MathMLWriter) end(b *strings.Builder) {
+	b.WriteString("</mrow>")
+}
@@ -0,0 +1,80 @@
+package logic
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTypeset(t *testing.T) {
+	source := CreateBasicParticleSource()
+	x := source.GetVariableNamed("x")
+	y := source.GetVariableNamed("y")
+	all := source.GetQuantifier(QUANT_FORALL)
+	and := source.GetOperator(OP_AND)
+	or := source.GetOperator(OP_OR)
+	not := source.GetOperator(OP_NOT)
+	impl := source.GetOperator(OP_IMPLIES)
+	eq := source.GetPredicateName(PRED_EQUALS)
+	atom := func(name string, args ...Particle) Particle {
+		return source.GetAtomicPredicate(source.GetPredicateName(name), args...)
+	}
+	P, Q, R := atom("P"), atom("Q"), atom("R")
+	cases := []struct {
+		p Particle
+		latex string
+		mathml string
+	}{
+		{source.GetQuantifiedPredicate(all, x, source.GetPredicateExpression(impl,
+			atom("Foo", x), source.GetAtomicPredicate(eq, x, y))),
+			`\forall x.\, Foo(x) \rightarrow x = y`,
+			`<mrow><mo>∀</mo><mi>x</mi><mo>.</mo><mrow><mrow><mi>Foo</mi><mo>⁡</mo><mo>(</mo><mi>x</mi><mo>)</mo></mrow>` +
+				`<mo>→</mo><mrow><mi>x</mi><mo>=</mo><mi>y</mi></mrow></mrow></mrow>`},
+		{source.GetPredicateExpression(and, source.GetPredicateExpression(or, P, Q), R),
+			`(P \lor Q) \land R`,
+			`<mrow><mrow><mo>(</mo><mrow><mi>P</mi><mo>∨</mo><mi>Q</mi></mrow><mo>)</mo></mrow><mo>∧</mo><mi>R</mi></mrow>`},
+		{source.GetPredicateExpression(or, P, source.GetPredicateExpression(and, Q, R)),
+			`P \lor Q \land R`,
+			`<mrow><mi>P</mi><mo>∨</mo><mrow><mi>Q</mi><mo>∧</mo><mi>R</mi></mrow></mrow>`},
+		{source.GetPredicateExpression(not, atom("a_b&c", x)),
+			`\neg a\_b\&c(x)`,
+			`<mrow><mo>¬</mo><mrow><mi>a_b&amp;c</mi><mo>⁡</mo><mo>(</mo><mi>x</mi><mo>)</mo></mrow></mrow>`},
+		{source.GetPredicateExpression(source.GetOperator("xor"), P, Q),
+			`\{xor:\ P, Q\}`,
+			`<mrow><mo>{</mo><mi>xor</mi><mo>:</mo><mi>P</mi><mo>,</mo><mi>Q</mi><mo>}</mo></mrow>`},
+	}
+	lw := GetLaTeXWriter()
+	mw := GetMathMLWriter()
+	for _, c := range cases {
+		var buf bytes.Buffer
+		if err := lw.Write(c.p, &buf); err != nil {
+			t.Errorf("%s: %s", c.latex, err.Error())
+		} else if buf.String() != c.latex {
+			t.Errorf("wrote '%s', expected '%s'", buf.String(), c.latex)
+		}
+		buf.Reset()
+		expected := `<math xmlns="http://www.w3.org/1998/Math/MathML">` + c.mathml + `</math>`
+		if err := mw.Write(c.p, &buf); err != nil {
+			t.Errorf("%s: %s", c.latex, err.Error())
+		} else if buf.String() != expected {
+			t.Errorf("wrote '%s', expected '%s'", buf.String(), expected)
+		}
+	}
+
+	lw.Symbols[OP_IMPLIES] = `\supset`
+	lw.Quantifiers[QUANT_FORALL] = `\Pi`
+	var buf bytes.Buffer
+	lw.Write(source.GetQuantifiedPredicate(all, x, source.GetPredicateExpression(impl, P, Q)), &buf)
+	if buf.String() != `\Pi x.\, P \supset Q` {
+		t.Errorf("wrote '%s' with custom symbols", buf.String())
+	}
+	delete(lw.Quantifiers, QUANT_FORALL)
+	if err := lw.Write(source.GetQuantifiedPredicate(all, x, P), &buf); err == nil {
+		t.Errorf("wrote quantifier without a symbol")
+	}
+	mw.Display = true
+	buf.Reset()
+	mw.Write(x, &buf)
+	if buf.String() != `<math xmlns="http://www.w3.org/1998/Math/MathML" display="block"><mi>x</mi></math>` {
+		t.Errorf("wrote '%s' in display mode", buf.String())
+	}
+}
@@ -0,0 +1,254 @@
+package logic
+
+// Unify and UnifyLinear compute a most general unifier of two terms, or of
+// two atomic predicates: a substitution which makes them equal, and of which
+// every other such substitution is an instance.  The terms are variables and
+// function expressions; particles containing other types do not unify.  The
+// unifier is idempotent, maps variables in order of their first occurrence
+// in a and then b, and builds its terms with the sources of a and b.
+//
+// Unify applies the rules of Martelli and Montanari to a set of equations,
+// with an occurs check before each variable is bound, and can take time
+// exponential in the size of the terms when they share subterms.
+// UnifyLinear merges classes of the subterms of a and b with union-find,
+// checking for cycles once at the end, and takes almost linear time.
+
+// unifiable reports whether p contains only the types Unify handles; an
+// atomic predicate is allowed only at the root.
+func unifiable(p Particle, root bool) bool {
+	switch(p.Type()) {
+		case VARIABLE: return true
+		case ATOMIC_PREDICATE: {
+			if !root {
+				return false
+			}
+		}
+		case FUNCTION_EXPRESSION:
+		default: return false
+	}
+	for _, arg := range p.(TupleParticle).Arguments() {
+		if !unifiable(arg, false) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameFunctor reports whether tuples a and b have the same type, head and
+// arity.
+func sameFunctor(a TupleParticle, b TupleParticle) bool {
+	return a.Type() == b.Type() && a.Head().Equals(b.Head()) && a.Arity() == b.Arity()
+}
+
+func occurs(v NamedParticle, p Particle) bool {
+	if p.Type() == VARIABLE {
+		return p.(NamedParticle).String() == v.String()
+	}
+	for _, arg := range p.(TupleParticle).Arguments() {
+		if occurs(v, arg) {
+			return true
+		}
+	}
+	return false
+}
+
+// unifierOrder returns the variables of a and then b, in order of first
+// occurrence.
+func unifierOrder(a Particle, b Particle) []NamedParticle {
+	vars := FreeVariables(a)
+	seen := make(map[string]bool)
+	for _, v := range vars {
+		seen[v.String()] = true
+	}
+	for _, v := range FreeVariables(b) {
+		if !seen[v.String()] {
+			vars = append(vars, v)
+		}
+	}
+	return vars
+}
+
+func Unify(a Particle, b Particle) (*Substitution, bool) {
+	if !unifiable(a, true) || !unifiable(b, true) || a.Term() != b.Term() {
+		return nil, false
+	}
+	sigma := CreateSubstitution()
+	eqs := [][2]Particle{{a, b}}
+	for len(eqs) > 0 {
+		s, t := sigma.Apply(eqs[len(eqs)-1][0]), sigma.Apply(eqs[len(eqs)-1][1])
+		eqs = eqs[:len(eqs)-1]
+		if s.Type() != VARIABLE && t.Type() == VARIABLE {
+			s, t = t, s
+		}
+		if s.Type() == VARIABLE {
+			v := s.(NamedParticle)
+			if t.Type() == VARIABLE && t.(NamedParticle).String() == v.String() {
+				continue
+			}
+			if occurs(v, t) {
+				return nil, false
+			}
+			sigma = sigma.Compose(CreateSubstitution().Bind(v, t))
+			continue
+		}
+		st, tt := s.(TupleParticle), t.(TupleParticle)
+		if !sameFunctor(st, tt) {
+			return nil, false
+		}
+		for i := st.Arity()-1; i >= 0; i-- {
+			eqs = append(eqs, [2]Particle{st.Argument(i), tt.Argument(i)})
+		}
+	}
+	mgu := CreateSubstitution()
+	for _, v := range unifierOrder(a, b) {
+		if t, ok := sigma.Lookup(v); ok {
+			mgu.Bind(v, t)
+		}
+	}
+	return mgu, true
+}
+
+// unifyNode is a distinct subterm in UnifyLinear.  Each class of nodes has
+// a representative, which holds the tuple node of the class, if there is
+// one, as its schema.
+type unifyNode struct {
+	p Particle
+	args []int
+	parent int
+	size int
+	schema int
+}
+
+type unifier struct {
+	table *particleTable
+	nodes []*unifyNode
+}
+
+// node returns the index of the node for p, adding it and its arguments.
+func (u *unifier) node(p Particle) int {
+	if i, ok := u.table.index(p); ok {
+		return i
+	}
+	n := &unifyNode{p: p, size: 1, schema: -1}
+	if p.Type() != VARIABLE {
+		for _, arg := range p.(TupleParticle).Arguments() {
+			n.args = append(n.args, u.node(arg))
+		}
+	}
+	i := u.table.add(p)
+	n.parent = i
+	if p.Type() != VARIABLE {
+		n.schema = i
+	}
+	u.nodes = append(u.nodes, n)
+	return i
+}
+
+func (u *unifier) find(i int) int {
+	for u.nodes[i].parent != i {
+		u.nodes[i].parent = u.nodes[u.nodes[i].parent].parent
+		i = u.nodes[i].parent
+	}
+	return i
+}
+
+// union merges the classes of i and j and those their schemas require.
+func (u *unifier) union(i int, j int) bool {
+	pairs := [][2]int{{i, j}}
+	for len(pairs) > 0 {
+		a, b := u.find(pairs[len(pairs)-1][0]), u.find(pairs[len(pairs)-1][1])
+		pairs = pairs[:len(pairs)-1]
+		if a == b {
+			continue
+		}
+		if u.nodes[a].size < u.nodes[b].size {
+			a, b = b, a
+		}
+		sa, sb := u.nodes[a].schema, u.nodes[b].schema
+		u.nodes[b].parent = a
+		u.nodes[a].size += u.nodes[b].size
+		switch {
+			case sa < 0: u.nodes[a].schema = sb
+			case sb >= 0: {
+				if !sameFunctor(u.nodes[sa].p.(TupleParticle), u.nodes[sb].p.(TupleParticle)) {
+					return false
+				}
+				for k := range u.nodes[sa].args {
+					pairs = append(pairs, [2]int{u.nodes[sa].args[k], u.nodes[sb].args[k]})
+				}
+			}
+		}
+	}
+	return true
+}
+
+// acyclic reports whether no class contains a term of itself, which is the
+// occurs check.
+func (u *unifier) acyclic() bool {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(u.nodes))
+	var visit func(c int) bool
+	visit = func(c int) bool {
+		switch(state[c]) {
+			case visiting: return false
+			case done: return true
+		}
+		state[c] = visiting
+		if s := u.nodes[c].schema; s >= 0 {
+			for _, arg := range u.nodes[s].args {
+				if !visit(u.find(arg)) {
+					return false
+				}
+			}
+		}
+		state[c] = done
+		return true
+	}
+	for i := range u.nodes {
+		if !visit(u.find(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+func UnifyLinear(a Particle, b Particle) (*Substitution, bool) {
+	if !unifiable(a, true) || !unifiable(b, true) || a.Term() != b.Term() {
+		return nil, false
+	}
+	u := &unifier{table: newParticleTable()}
+	if !u.union(u.node(a), u.node(b)) || !u.acyclic() {
+		return nil, false
+	}
+	order := unifierOrder(a, b)
+	reps := make(map[int]Particle)
+	for _, v := range order {
+		i, _ := u.table.index(v)
+		if c := u.find(i); u.nodes[c].schema < 0 && reps[c] == nil {
+			reps[c] = v
+		}
+	}
+	var build func(c int) Particle
+	build = func(c int) Particle {
+		if p, ok := reps[c]; ok {
+			return p
+		}
+		tp := u.nodes[u.nodes[c].schema].p.(TupleParticle)
+		args := make([]Particle, len(u.nodes[u.nodes[c].schema].args))
+		for k, arg := range u.nodes[u.nodes[c].schema].args {
+			args[k] = build(u.find(arg))
+		}
+		reps[c] = tp.Source().GetTuple(tp.Type(), tp.Head(), args...)
+		return reps[c]
+	}
+	mgu := CreateSubstitution()
+	for _, v := range order {
+		i, _ := u.table.index(v)
+		mgu.Bind(v, build(u.find(i)))
+	}
+	return mgu, true
+}
@@ -0,0 +1,96 @@
+package logic
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUnify(t *testing.T) {
+	source := CreateBasicParticleSource()
+	cases := []struct {
+		a string
+		b string
+		mgu string
+		linear string
+	}{
+		{"P(x, f(y))", "P(g(z), f(a()))", "{$x -> g($z), $y -> a()}", ""},
+		{"P(x, y, x)", "P(f(y), g(z), w)", "{$x -> f(g($z)), $y -> g($z), $w -> f(g($z))}", ""},
+		{"P(x)", "P(y)", "{$x -> $y}", "{$y -> $x}"},
+		{"P(x, y)", "P(y, x)", "{$x -> $y}", "{$y -> $x}"},
+		{"P(f(x), x)", "P(f(y), g(y))", "", ""},
+		{"P(x, x)", "P(y, f(y))", "", ""},
+		{"P(f(x))", "P(g(x))", "", ""},
+		{"P(x)", "Q(x)", "", ""},
+		{"P(x)", "P(x, y)", "", ""},
+		{"P(c())", "P(c())", "{}", ""},
+	}
+	for _, c := range cases {
+		a, b := readInfix(t, source, c.a, false), readInfix(t, source, c.b, false)
+		linear := c.linear
+		if linear == "" {
+			linear = c.mgu
+		}
+		for _, u := range []struct {
+			name string
+			unify func(a Particle, b Particle) (*Substitution, bool)
+			expected string
+		}{{"Unify", Unify, c.mgu}, {"UnifyLinear", UnifyLinear, linear}} {
+			s, ok := u.unify(a, b)
+			switch {
+				case !ok && u.expected != "": t.Errorf("%s failed on '%s' and '%s'", u.name, c.a, c.b)
+				case ok && u.expected == "": t.Errorf("%s unified '%s' and '%s' by %s", u.name, c.a, c.b, s.String())
+				case ok && s.String() != u.expected: t.Errorf("%s unified '%s' and '%s' by %s", u.name, c.a, c.b, s.String())
+				case ok && !s.Apply(a).Equals(s.Apply(b)): t.Errorf("%s did not unify '%s' and '%s'", u.name, c.a, c.b)
+			}
+		}
+	}
+
+	x, y := source.GetVariableNamed("x"), source.GetVariableNamed("y")
+	fy := source.GetFunctionExpression(source.GetFunctionName("f"), y)
+	if _, ok := Unify(x, source.GetAtomicPredicate(source.GetPredicateName("P"), x)); ok {
+		t.Errorf("unified a term with a predicate")
+	}
+	for _, unify := range []func(a Particle, b Particle) (*Substitution, bool){Unify, UnifyLinear} {
+		if s, ok := unify(fy, x); !ok || s.String() != "{$x -> f($y)}" {
+			t.Errorf("failed to unify terms")
+		}
+		if _, ok := unify(x, source.GetFunctionExpression(source.GetFunctionName("g"), fy, source.GetFunctionExpression(source.GetFunctionName("f"), x))); ok {
+			t.Errorf("unified terms failing the occurs check")
+		}
+	}
+}
+
+// The terms f(x1, ..., xn) and f(g(x0, x0), ..., g(xn-1, xn-1)) have a
+// unifier whose terms are exponentially large unless shared.
+func TestUnifyLinearShared(t *testing.T) {
+	source := CreateBasicParticleSource()
+	f, g := source.GetFunctionName("f"), source.GetFunctionName("g")
+	n := 40
+	vars := make([]Particle, n+1)
+	for i := range vars {
+		vars[i] = source.GetVariableNamed(fmt.Sprintf("x%d", i))
+	}
+	gs := make([]Particle, n)
+	for i := range gs {
+		gs[i] = source.GetFunctionExpression(g, vars[i], vars[i])
+	}
+	s, ok := UnifyLinear(source.GetFunctionExpression(f, vars[1:]...), source.GetFunctionExpression(f, gs...))
+	if !ok || s.Len() != n {
+		t.Fatalf("failed to unify")
+	}
+	xn, _ := s.Lookup(vars[n].(NamedParticle))
+	for i := n; i > 0; i-- {
+		tp := xn.(TupleParticle)
+		if tp.Argument(0) != tp.Argument(1) {
+			t.Fatalf("unifier does not share subterms")
+		}
+		xn = tp.Argument(0)
+	}
+	if !xn.Equals(vars[0]) {
+		t.Errorf("unifier is wrong")
+	}
+	gs[0] = source.GetFunctionExpression(g, vars[0], vars[n])
+	if _, ok := UnifyLinear(source.GetFunctionExpression(f, vars[1:]...), source.GetFunctionExpression(f, gs...)); ok {
+		t.Errorf("unified terms with a cycle")
+	}
+}
@@ -0,0 +1,77 @@
+package logic
+
+// FreeVariables returns the variables with an occurrence in p that is not
+// bound by an enclosing quantifier, in order of their first free occurrence.
+func FreeVariables(p Particle) []NamedParticle {
+	var free []NamedParticle
+	seen := make(map[string]bool)
+	var walk func(p Particle, bound []string)
+	walk = func(p Particle, bound []string) {
+		switch(p.Type()) {
+			case VARIABLE: {
+				v := p.(NamedParticle)
+				for _, b := range bound {
+					if b == v.String() {
+						return
+					}
+				}
+				if !seen[v.String()] {
+					seen[v.String()] = true
+					free = append(free, v)
+				}
+			}
+			case QUANTIFIED_TERM, QUANTIFIED_PREDICATE: {
+				qp := p.(QuantifiedParticle)
+				walk(qp.Argument(), append(bound[:len(bound):len(bound)], qp.Variable().String()))
+			}
+			default: {
+				for _, part := range p.Parts() {
+					walk(part, bound)
+				}
+			}
+		}
+	}
+	walk(p, nil)
+	return free
+}
+
+// BoundVariables returns the variables bound by the quantifiers in p, in the
+// order the quantifiers appear.
+func BoundVariables(p Particle) []NamedParticle {
+	var bound []NamedParticle
+	seen := make(map[string]bool)
+	var walk func(p Particle)
+	walk = func(p Particle) {
+		if qp, ok := p.(QuantifiedParticle); ok && (p.Type() == QUANTIFIED_TERM || p.Type() == QUANTIFIED_PREDICATE) {
+			if v := qp.Variable(); !seen[v.String()] {
+				seen[v.String()] = true
+				bound = append(bound, v)
+			}
+			walk(qp.Argument())
+			return
+		}
+		for _, part := range p.Parts() {
+			walk(part)
+		}
+	}
+	walk(p)
+	return bound
+}
+
+// IsClosed reports whether p has no free variables.
+func IsClosed(p Particle) bool {
+	return len(FreeVariables(p)) == 0
+}
+
+// IsGround reports whether p has no variables, free or bound.
+func IsGround(p Particle) bool {
+	if p.Type() == VARIABLE {
+		return false
+	}
+	for _, part := range p.Parts() {
+		if !IsGround(part) {
+			return false
+		}
+	}
+	return true
+}
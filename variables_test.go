@@ -0,0 +1,50 @@
+package logic
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func names(vs []NamedParticle) string {
+	s := make([]string, len(vs))
+	for i, v := range vs {
+		s[i] = v.String()
+	}
+	return strings.Join(s, " ")
+}
+
+func TestVariables(t *testing.T) {
+	cases := []struct {
+		text string
+		free string
+		bound string
+		ground bool
+	}{
+		{"forall x. P(x, y) & Q(z, y)", "y z", "x", false},
+		{"P(x) & exists x. Q(x, y)", "x y", "x", false},
+		{"forall x y. exists x. P(x, y)", "", "x y", false},
+		{"P(c(), f(c())) | ~Q", "", "", true},
+		{"x = f(y, x)", "x y", "", false},
+	}
+	for _, source := range []ParticleSource{CreateBasicParticleSource(), CreateInterningParticleSource()} {
+		for _, c := range cases {
+			p, err := GetInfixReader().ReadPredicate(source, bufio.NewReader(strings.NewReader(c.text)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if free := names(FreeVariables(p)); free != c.free {
+				t.Errorf("free variables of '%s' are '%s'", c.text, free)
+			}
+			if bound := names(BoundVariables(p)); bound != c.bound {
+				t.Errorf("bound variables of '%s' are '%s'", c.text, bound)
+			}
+			if IsClosed(p) != (c.free == "") {
+				t.Errorf("'%s' closed: %v", c.text, IsClosed(p))
+			}
+			if IsGround(p) != c.ground {
+				t.Errorf("'%s' ground: %v", c.text, IsGround(p))
+			}
+		}
+	}
+}